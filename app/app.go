@@ -0,0 +1,299 @@
+// Package app provides the root model of Hermit. It keeps the tabs of open
+// screens and any open dialog, and is the place for state that is shared by
+// all screens.
+package app
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/nav"
+	"github.com/ancientlore/hermit2/theme"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Styles of the tab bar.
+var (
+	tabBar    = theme.Default().Footer
+	activeTab = theme.Default().Cursor
+)
+
+// SetTheme sets the styles of the tab bar.
+func SetTheme(t theme.Theme) {
+	tabBar = t.Footer
+	activeTab = t.Cursor
+}
+
+// Titler is implemented by screens that name the tab they are in.
+type Titler interface {
+	Title() string
+}
+
+// Broadcaster is implemented by messages from work in the background, such
+// as jobs. They go to every open screen, in every tab, since the screen
+// that started the work may no longer be shown.
+type Broadcaster interface {
+	Broadcast()
+}
+
+// Model coordinates the screens. Each tab has its own stack of screens, and
+// the one on top of the stack of the current tab is shown. Keys go to the
+// open dialog, if there is one, and otherwise to that screen, as do other
+// messages unless they are a Broadcaster. Screens are opened and closed
+// with the messages in the nav package, and are closed with Close, if they
+// have it, when they leave the stack. When there is more than one tab, a
+// line above the screen shows the tabs. A dialog opened while another is
+// open waits until that one closes.
+type Model struct {
+	tabs   [][]tea.Model     // Stacks of open screens, one per tab
+	active int               // The tab shown
+	dialog dialog.Dialog     // The open dialog, if any
+	queue  []dialog.Dialog   // Dialogs waiting to open, in order
+	size   tea.WindowSizeMsg // The last window size
+}
+
+// New creates the root model with its first screen.
+func New(root tea.Model) Model {
+	return NewTabs([]tea.Model{root}, 0)
+}
+
+// NewTabs creates the root model with a tab for each of the first screens,
+// showing the active one.
+func NewTabs(roots []tea.Model, active int) Model {
+	m := Model{active: max(min(active, len(roots)-1), 0)}
+	for _, s := range roots {
+		m.tabs = append(m.tabs, []tea.Model{s})
+	}
+	return m
+}
+
+// Tabs returns the first screen of each tab and the index of the tab shown.
+func (m Model) Tabs() ([]tea.Model, int) {
+	roots := make([]tea.Model, len(m.tabs))
+	for i, tab := range m.tabs {
+		roots[i] = tab[0]
+	}
+	return roots, m.active
+}
+
+func (m Model) Init() tea.Cmd {
+	cmds := make([]tea.Cmd, len(m.tabs))
+	for i, tab := range m.tabs {
+		cmds[i] = tab[0].Init()
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.size = msg
+		return m, m.resize()
+
+	case nav.PushMsg:
+		return m, m.push(msg.Model)
+
+	case nav.PopMsg:
+		m.pop()
+		return m, nil
+
+	case nav.NewTabMsg:
+		return m, m.newTab(msg.Model)
+
+	case nav.CloseTabMsg:
+		return m, m.closeTab()
+
+	case nav.CycleTabMsg:
+		n := len(m.tabs)
+		m.active = ((m.active+msg.Step)%n + n) % n
+		return m, nil
+
+	case nav.QuitMsg:
+		for _, tab := range m.tabs {
+			closeStack(tab)
+		}
+		return m, tea.Quit
+
+	case dialog.OpenMsg:
+		if m.dialog == nil {
+			m.dialog = msg.Dialog
+		} else {
+			m.queue = append(m.queue, msg.Dialog)
+		}
+		return m, nil
+
+	case Broadcaster:
+		return m, m.broadcast(msg)
+	}
+
+	// An open dialog takes the user's input and sees other messages too,
+	// such as cursor blinks
+	var dlgCmd tea.Cmd
+	if m.dialog != nil {
+		m.dialog, dlgCmd = m.dialog.Update(msg)
+		if m.dialog == nil && len(m.queue) > 0 {
+			m.dialog, m.queue = m.queue[0], m.queue[1:]
+		}
+		if dialog.Captures(msg) {
+			return m, dlgCmd
+		}
+	}
+
+	var cmd tea.Cmd
+	stack := m.tabs[m.active]
+	stack[len(stack)-1], cmd = m.top().Update(msg)
+	return m, tea.Batch(dlgCmd, cmd)
+}
+
+// View renders the screen on top of the stack, with any open dialog over it.
+func (m Model) View() tea.View {
+	v := m.top().View()
+	if len(m.tabs) > 1 {
+		v.Content = m.tabBar() + "\n" + v.Content
+	}
+	if m.dialog != nil {
+		v.Content = dialog.Overlay(v.Content, m.dialog)
+	}
+	return v
+}
+
+// tabBar renders the line that shows the tabs, numbered from 1 and named by
+// their screens.
+func (m Model) tabBar() string {
+	var b strings.Builder
+	for i, tab := range m.tabs {
+		label := fmt.Sprintf(" %d %s ", i+1, title(tab))
+		if i == m.active {
+			b.WriteString(activeTab.Render(label))
+		} else {
+			b.WriteString(tabBar.Render(label))
+		}
+	}
+	return tabBar.Width(m.size.Width).MaxWidth(m.size.Width).MaxHeight(1).Render(b.String())
+}
+
+// title returns the name of a tab, which comes from the screen nearest the
+// top of its stack that has one.
+func title(stack []tea.Model) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if t, ok := stack[i].(Titler); ok {
+			return t.Title()
+		}
+	}
+	return ""
+}
+
+// top returns the screen being shown.
+func (m Model) top() tea.Model {
+	stack := m.tabs[m.active]
+	return stack[len(stack)-1]
+}
+
+// screenSize returns the size available to screens, which is the window
+// less the tab bar when it is shown.
+func (m Model) screenSize() tea.WindowSizeMsg {
+	size := m.size
+	if len(m.tabs) > 1 {
+		size.Height = max(size.Height-1, 0)
+	}
+	return size
+}
+
+// resize gives every screen the size available to it.
+func (m *Model) resize() tea.Cmd {
+	if m.size.Width == 0 {
+		return nil
+	}
+	return m.broadcast(m.screenSize())
+}
+
+// broadcast passes a message to every screen.
+func (m *Model) broadcast(msg tea.Msg) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, tab := range m.tabs {
+		for i := range tab {
+			var cmd tea.Cmd
+			tab[i], cmd = tab[i].Update(msg)
+			cmds = append(cmds, cmd)
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// open initializes a screen and gives it the size available to it.
+func (m *Model) open(s tea.Model) (tea.Model, tea.Cmd) {
+	cmds := []tea.Cmd{s.Init()}
+	if m.size.Width > 0 {
+		var cmd tea.Cmd
+		s, cmd = s.Update(m.screenSize())
+		cmds = append(cmds, cmd)
+	}
+	return s, tea.Batch(cmds...)
+}
+
+// push opens a screen over the current one in the current tab.
+func (m *Model) push(s tea.Model) tea.Cmd {
+	s, cmd := m.open(s)
+	m.tabs[m.active] = append(m.tabs[m.active], s)
+	return cmd
+}
+
+// pop closes the screen on top of the stack of the current tab, unless it
+// is the last one.
+func (m *Model) pop() {
+	stack := m.tabs[m.active]
+	if len(stack) < 2 {
+		return
+	}
+	closeScreen(stack[len(stack)-1])
+	m.tabs[m.active] = stack[:len(stack)-1]
+}
+
+// newTab opens a screen in a new tab after the current one and shows it.
+func (m *Model) newTab(s tea.Model) tea.Cmd {
+	m.tabs = slices.Insert(m.tabs, m.active+1, []tea.Model{s})
+	m.active++
+	if len(m.tabs) == 2 {
+		// The tab bar takes a line from every screen
+		return tea.Batch(s.Init(), m.resize())
+	}
+	var cmd tea.Cmd
+	m.tabs[m.active][0], cmd = m.open(s)
+	return cmd
+}
+
+// closeTab closes the current tab and its screens, unless it is the last
+// one, and shows the tab before it.
+func (m *Model) closeTab() tea.Cmd {
+	if len(m.tabs) < 2 {
+		return nil
+	}
+	closeStack(m.tabs[m.active])
+	m.tabs = slices.Delete(m.tabs, m.active, m.active+1)
+	m.active = max(m.active-1, 0)
+	if len(m.tabs) == 1 {
+		// The tab bar is no longer shown
+		return m.resize()
+	}
+	return nil
+}
+
+// closeStack closes the screens of a tab, from the top down.
+func closeStack(stack []tea.Model) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		closeScreen(stack[i])
+	}
+}
+
+// closeScreen closes a screen that is leaving the stack.
+func closeScreen(s tea.Model) {
+	if c, ok := s.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Print(err)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+// Package archivefs opens zip and tar archives as file systems, so that
+// they can be browsed like folders.
+package archivefs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// maxMemberSize is the largest member that is read into memory, which is
+// needed for members of compressed archives and archives within archives.
+const maxMemberSize = 256 << 20
+
+// maxLinkSize is the longest destination of a symbolic link read from a
+// zip archive.
+const maxLinkSize = 4096
+
+// ErrTooLarge is returned when a file is too large to read into memory.
+var ErrTooLarge = errors.New("too large to open inside a compressed archive")
+
+// kind is a type of archive.
+type kind int
+
+const (
+	notArchive kind = iota
+	zipArchive
+	tarArchive
+	tgzArchive
+)
+
+// kindOf returns the type of archive a file is, by its extension.
+func kindOf(name string) kind {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return zipArchive
+	case strings.HasSuffix(lower, ".tar"):
+		return tarArchive
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return tgzArchive
+	}
+	return notArchive
+}
+
+// IsArchive reports whether a file name has the extension of a supported
+// archive: .zip, .jar, .tar, .tar.gz or .tgz.
+func IsArchive(name string) bool {
+	return kindOf(name) != notArchive
+}
+
+// TrimExt returns the name of an archive without its extension.
+func TrimExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip", ".jar"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// IsFS reports whether a file system is the contents of an archive.
+func IsFS(fsys fs.FS) bool {
+	return handleOf(fsys) != nil
+}
+
+// handleOf returns the handle of the archive that fsys is the contents of,
+// or nil if it is not an archive.
+func handleOf(fsys fs.FS) *handle {
+	switch a := fsys.(type) {
+	case zipFS:
+		return a.h
+	case *tarFS:
+		return a.h
+	}
+	return nil
+}
+
+// Hold keeps the archive that fsys is the contents of open until the
+// returned function is called, even if it is closed in the meantime, so
+// that background work can finish. It does nothing for other file systems.
+func Hold(fsys fs.FS) func() {
+	h := handleOf(fsys)
+	if h == nil {
+		return func() {}
+	}
+	release := h.hold()
+	return func() { release() }
+}
+
+// handle closes an archive file once nothing uses it.
+type handle struct {
+	mu     sync.Mutex
+	refs   int
+	f      io.Closer
+	parent func() error // Releases the archive this one is inside, if any
+}
+
+// hold adds a use of the archive. The returned function ends it, and
+// does nothing if called again.
+func (h *handle) hold() func() error {
+	h.mu.Lock()
+	h.refs++
+	h.mu.Unlock()
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() { err = h.release() })
+		return err
+	}
+}
+
+// release ends a use of the archive, closing it after the last one.
+func (h *handle) release() error {
+	h.mu.Lock()
+	h.refs--
+	last := h.refs == 0
+	h.mu.Unlock()
+	if !last {
+		return nil
+	}
+	err := h.f.Close()
+	if h.parent != nil {
+		err = errors.Join(err, h.parent())
+	}
+	return err
+}
+
+// closerFunc is a function that closes something.
+type closerFunc func() error
+
+func (c closerFunc) Close() error {
+	return c()
+}
+
+// Open opens the archive called name in fsys as a file system. The archive
+// may itself be inside an archive. The closer closes the archive file,
+// unless it is held by Hold.
+func Open(fsys fs.FS, name string) (fs.FS, io.Closer, error) {
+	k := kindOf(name)
+	if k == notArchive {
+		return nil, nil, fmt.Errorf("%s is not a supported archive", name)
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		// Archives that can't be read at any offset are read into memory
+		b, err := readAll(f, info.Size())
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		ra = bytes.NewReader(b)
+	}
+
+	h := &handle{f: f}
+	var afs fs.FS
+	switch k {
+	case zipArchive:
+		var zr *zip.Reader
+		zr, err = zip.NewReader(ra, info.Size())
+		afs = zipFS{zr, h}
+	default:
+		afs, err = newTarFS(ra, info.Size(), k == tgzArchive, h)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	// An archive inside another one may be read from it, so the outer
+	// one is kept open until this one is closed
+	if p := handleOf(fsys); p != nil {
+		h.parent = p.hold()
+	}
+	return afs, closerFunc(h.hold()), nil
+}
+
+// readAll reads a member of the given size into memory.
+func readAll(r io.Reader, size int64) ([]byte, error) {
+	if size > maxMemberSize {
+		return nil, ErrTooLarge
+	}
+	b, err := io.ReadAll(io.LimitReader(r, maxMemberSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxMemberSize {
+		return nil, ErrTooLarge
+	}
+	return b, nil
+}
+
+// file is an archive member that can be read at any offset, so that it
+// can be viewed like a file on disk.
+type file struct {
+	*io.SectionReader
+	info fs.FileInfo
+}
+
+func (f file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f file) Close() error {
+	return nil
+}
+
+// memFile returns a member read into memory.
+func memFile(b []byte, info fs.FileInfo) file {
+	return file{io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))), info}
+}
+
+// zipFS is the contents of a zip archive. Members are decompressed into
+// memory when they are opened.
+type zipFS struct {
+	r *zip.Reader
+	h *handle
+}
+
+func (z zipFS) Open(name string) (fs.File, error) {
+	f, err := z.r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		return f, nil
+	}
+	defer f.Close()
+	b, err := readAll(f, info.Size())
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return memFile(b, info), nil
+}
+
+// Stat returns information about a member without decompressing it.
+func (z zipFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := z.r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// ReadLink returns the destination of a symbolic link, which zip archives
+// store as the contents of the member.
+func (z zipFS) ReadLink(name string) (string, error) {
+	info, err := z.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	b, err := fs.ReadFile(z.r, name)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Lstat returns information about a member. Symbolic links are never
+// followed in zip archives.
+func (z zipFS) Lstat(name string) (fs.FileInfo, error) {
+	return z.Stat(name)
+}
+
+// Member is an entry of a file system, as given by Walk.
+type Member struct {
+	Name string      // Path from the folder walked, or "." for the folder itself
+	Info fs.FileInfo // Information about the entry, not following a link
+	Link string      // Destination of a symbolic link
+
+	// Open opens the contents of a regular file. It may only be called
+	// before the function given to Walk returns.
+	Open func() (io.ReadCloser, error)
+}
+
+// Walk calls fn for name and, if it is a folder, for everything in it.
+// Archives are read once, in the order their members are stored, so that
+// the contents of members are streamed rather than read into memory.
+// Folders that are only implied by the paths of members are not given,
+// except for name itself. Other file systems are walked with fs.WalkDir.
+// Walk stops at the first error returned by fn.
+func Walk(fsys fs.FS, name string, fn func(m Member) error) error {
+	switch a := fsys.(type) {
+	case zipFS:
+		return a.walk(name, fn)
+	case *tarFS:
+		return a.walk(name, fn)
+	}
+	return fs.WalkDir(fsys, name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := relative(name, p)
+		m := Member{Name: rel, Info: info}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if m.Link, err = fs.ReadLink(fsys, p); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			m.Open = func() (io.ReadCloser, error) { return fsys.Open(p) }
+		}
+		return fn(m)
+	})
+}
+
+// relative returns the path p relative to the folder dir, and whether p is
+// dir or inside it.
+func relative(dir, p string) (string, bool) {
+	switch {
+	case p == dir:
+		return ".", true
+	case dir == ".":
+		return p, true
+	case strings.HasPrefix(p, dir+"/"):
+		return p[len(dir)+1:], true
+	}
+	return "", false
+}
+
+// walk streams the members of a zip archive, decompressing each as it is
+// read.
+func (z zipFS) walk(name string, fn func(m Member) error) error {
+	info, err := z.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		// The folder may be only implied by the paths of members
+		if err := fn(Member{Name: ".", Info: info}); err != nil {
+			return err
+		}
+	}
+	for _, f := range z.r.File {
+		// The names are cleaned as zip.Reader.Open does
+		rel, ok := relative(name, clean(strings.ReplaceAll(f.Name, `\`, "/")))
+		if !ok || rel == "." && info.IsDir() {
+			continue
+		}
+		fi := f.FileInfo()
+		m := Member{Name: rel, Info: fi}
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			// Zip archives keep the destination of a link as its contents
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			b, err := io.ReadAll(io.LimitReader(rc, maxLinkSize))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			m.Link = string(b)
+		case fi.Mode().IsRegular():
+			m.Open = f.Open
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
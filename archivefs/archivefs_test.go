@@ -0,0 +1,269 @@
+package archivefs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// member is an entry of a test archive.
+type member struct {
+	name string
+	link string // Destination, for a symbolic link
+	hard bool   // Whether link is a hard link
+	body string
+}
+
+// build returns an archive of the members, of the type named by ext.
+func build(t *testing.T, ext string, members []member) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if ext == ".zip" {
+		zw := zip.NewWriter(&buf)
+		for _, m := range members {
+			hdr := &zip.FileHeader{Name: m.name, Method: zip.Deflate}
+			body := m.body
+			switch {
+			case m.link != "":
+				hdr.SetMode(fs.ModeSymlink | 0o777)
+				body = m.link
+			case m.name[len(m.name)-1] == '/':
+				hdr.SetMode(fs.ModeDir | 0o755)
+			default:
+				hdr.SetMode(0o644)
+			}
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(body))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if ext == ".tgz" {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for _, m := range members {
+		hdr := &tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.body)), Typeflag: tar.TypeReg}
+		switch {
+		case m.hard:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, m.link, 0
+		case m.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, m.link, 0
+		case m.name[len(m.name)-1] == '/':
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(m.body))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		gz.Close()
+	}
+	return buf.Bytes()
+}
+
+// open opens an archive of the members as a file system.
+func open(t *testing.T, ext string, members []member) fs.FS {
+	t.Helper()
+	name := "a" + ext
+	afs, closer, err := Open(fstest.MapFS{name: {Data: build(t, ext, members)}}, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closer.Close() })
+	return afs
+}
+
+// names returns the names of the entries of a folder.
+func names(t *testing.T, fsys fs.FS, dir string) []string {
+	t.Helper()
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a []string
+	for _, e := range entries {
+		a = append(a, e.Name())
+	}
+	return a
+}
+
+func TestIndex(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar", ".tgz"} {
+		t.Run(ext, func(t *testing.T) {
+			// Folders a and a/b are only implied by the path of c.txt
+			afs := open(t, ext, []member{
+				{name: "a/b/c.txt", body: "c"},
+				{name: "d.txt", body: "dd"},
+				{name: "e/", body: ""},
+			})
+			if got, want := names(t, afs, "."), []string{"a", "d.txt", "e"}; !slices.Equal(got, want) {
+				t.Errorf("root has %q, want %q", got, want)
+			}
+			if got, want := names(t, afs, "a"), []string{"b"}; !slices.Equal(got, want) {
+				t.Errorf("a has %q, want %q", got, want)
+			}
+			for _, dir := range []string{"a", "a/b", "e"} {
+				if info, err := fs.Stat(afs, dir); err != nil || !info.IsDir() {
+					t.Errorf("Stat(%s) = %v, %v, want a folder", dir, info, err)
+				}
+			}
+			if b, err := fs.ReadFile(afs, "a/b/c.txt"); err != nil || string(b) != "c" {
+				t.Errorf("a/b/c.txt = %q, %v", b, err)
+			}
+			if info, err := fs.Stat(afs, "d.txt"); err != nil || info.Size() != 2 {
+				t.Errorf("Stat(d.txt) = %v, %v, want size 2", info, err)
+			}
+			if _, err := fs.Stat(afs, "a/missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(a/missing) = %v, want ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestTarLinks(t *testing.T) {
+	for _, ext := range []string{".tar", ".tgz"} {
+		t.Run(ext, func(t *testing.T) {
+			afs := open(t, ext, []member{
+				{name: "sub/f.txt", body: "f"},
+				{name: "ln", link: "sub"},
+				{name: "abs", link: "/sub"},
+				{name: "deep/up", link: "../ln"},
+				{name: "chain", link: "deep/up/f.txt"},
+				{name: "hard", link: "sub/f.txt", hard: true},
+				{name: "loop1", link: "loop2"},
+				{name: "loop2", link: "loop1"},
+			})
+			// Links are followed in every part of the path
+			for _, name := range []string{"ln/f.txt", "abs/f.txt", "deep/up/f.txt", "chain", "hard"} {
+				if b, err := fs.ReadFile(afs, name); err != nil || string(b) != "f" {
+					t.Errorf("%s = %q, %v, want f", name, b, err)
+				}
+			}
+			if got := names(t, afs, "deep/up"); !slices.Equal(got, []string{"f.txt"}) {
+				t.Errorf("deep/up has %q, want f.txt", got)
+			}
+			if info, err := fs.Lstat(afs, "ln"); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+				t.Errorf("Lstat(ln) = %v, %v, want a link", info, err)
+			}
+			if info, err := fs.Lstat(afs, "deep/up/f.txt"); err != nil || !info.Mode().IsRegular() {
+				t.Errorf("Lstat(deep/up/f.txt) = %v, %v, want a file", info, err)
+			}
+			if dest, err := fs.ReadLink(afs, "deep/up"); err != nil || dest != "../ln" {
+				t.Errorf("ReadLink(deep/up) = %q, %v", dest, err)
+			}
+			if info, err := fs.Stat(afs, "hard"); err != nil || info.Size() != 1 {
+				t.Errorf("Stat(hard) = %v, %v, want size 1", info, err)
+			}
+			for _, name := range []string{"loop1", "loop1/x", "ln/missing"} {
+				if _, err := fs.ReadFile(afs, name); err == nil {
+					t.Errorf("%s opened", name)
+				}
+			}
+		})
+	}
+}
+
+func TestZipLinks(t *testing.T) {
+	afs := open(t, ".zip", []member{
+		{name: "f.txt", body: "f"},
+		{name: "ln", link: "f.txt"},
+	})
+	if dest, err := fs.ReadLink(afs, "ln"); err != nil || dest != "f.txt" {
+		t.Errorf("ReadLink(ln) = %q, %v", dest, err)
+	}
+	if info, err := fs.Lstat(afs, "ln"); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat(ln) = %v, %v, want a link", info, err)
+	}
+	if _, err := fs.ReadLink(afs, "f.txt"); err == nil {
+		t.Error("ReadLink of a file succeeded")
+	}
+}
+
+// trackFS counts the files opened and closed in a file system.
+type trackFS struct {
+	fs.FS
+	open int
+}
+
+// trackFile is a file of a trackFS.
+type trackFile struct {
+	fs.File
+	fsys *trackFS
+}
+
+func (t *trackFS) Open(name string) (fs.File, error) {
+	f, err := t.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	t.open++
+	return trackFile{f, t}, nil
+}
+
+func (f trackFile) Close() error {
+	f.fsys.open--
+	return f.File.Close()
+}
+
+func TestNested(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar", ".tgz"} {
+		t.Run(ext, func(t *testing.T) {
+			inner := build(t, ".tgz", []member{{name: "x.txt", body: "x"}})
+			outer := "outer" + ext
+			disk := &trackFS{FS: fstest.MapFS{
+				outer: {Data: build(t, ext, []member{{name: "in/inner.tgz", body: string(inner)}})},
+			}}
+			ofs, oc, err := Open(disk, outer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ifs, ic, err := Open(ofs, "in/inner.tgz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsFS(ifs) || !IsArchive(path.Base(outer)) {
+				t.Error("nested archive not recognized")
+			}
+
+			// The outer archive stays open while the inner one is used
+			oc.Close()
+			if disk.open != 1 {
+				t.Errorf("%d files open after closing the outer archive, want 1", disk.open)
+			}
+			if b, err := fs.ReadFile(ifs, "x.txt"); err != nil || string(b) != "x" {
+				t.Errorf("x.txt = %q, %v", b, err)
+			}
+
+			// A hold keeps both open after the inner one is closed
+			release := Hold(ifs)
+			ic.Close()
+			ic.Close() // Closing again does nothing
+			if disk.open != 1 {
+				t.Errorf("%d files open while held, want 1", disk.open)
+			}
+			release()
+			if disk.open != 0 {
+				t.Errorf("%d files open after the last release, want 0", disk.open)
+			}
+		})
+	}
+}
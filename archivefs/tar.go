@@ -0,0 +1,399 @@
+package archivefs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxLinks is the most symbolic links followed when opening a member.
+const maxLinks = 255
+
+// tarFS is the contents of a tar archive, which may be compressed with
+// gzip. The headers are indexed when it is opened. Members of uncompressed
+// archives are read in place; those of compressed ones are found by
+// reading the archive again and are decompressed into memory.
+type tarFS struct {
+	ra    io.ReaderAt
+	size  int64
+	gzip  bool                // Whether the archive is compressed
+	files map[string]*tarFile // Members and folders by path, with "." the root
+	h     *handle
+}
+
+// tarFile is a member of a tar archive, or a folder that is only implied
+// by the paths of members.
+type tarFile struct {
+	name     string          // Path within the archive
+	hdr      *tar.Header     // Nil for implied folders
+	index    int             // Position of the header in the archive
+	offset   int64           // Where the data starts, if uncompressed
+	children map[string]bool // Names of the entries of a folder
+}
+
+// newTarFS indexes a tar archive.
+func newTarFS(ra io.ReaderAt, size int64, compressed bool, h *handle) (*tarFS, error) {
+	t := &tarFS{
+		ra:    ra,
+		size:  size,
+		gzip:  compressed,
+		files: map[string]*tarFile{".": {name: "."}},
+		h:     h,
+	}
+	err := t.scan(func(i int, hdr *tar.Header, offset int64, _ io.Reader) (bool, error) {
+		t.add(i, hdr, offset)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// scan reads the headers of the archive in order, calling fn for each one
+// with a reader for its data, until fn returns true or an error.
+func (t *tarFS) scan(fn func(i int, hdr *tar.Header, offset int64, r io.Reader) (bool, error)) error {
+	sr := io.NewSectionReader(t.ra, 0, t.size)
+	var r io.Reader = sr
+	if t.gzip {
+		zr, err := gzip.NewReader(sr)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var offset int64
+		if !t.gzip {
+			// The tar reader doesn't read ahead, so the data starts here
+			offset, _ = sr.Seek(0, io.SeekCurrent)
+		}
+		done, err := fn(i, hdr, offset, tr)
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+// clean converts a path in the archive into a valid fs.FS path.
+func clean(name string) string {
+	p := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// add adds a header to the index. Later headers for the same path replace
+// earlier ones, as when the archive is extracted.
+func (t *tarFS) add(i int, hdr *tar.Header, offset int64) {
+	if hdr.Typeflag == tar.TypeXGlobalHeader {
+		return
+	}
+	name := clean(hdr.Name)
+	if name == "." {
+		return
+	}
+	f := t.dir(name)
+	f.hdr, f.index, f.offset = hdr, i, offset
+}
+
+// dir returns the entry for a path, adding it and its parent folders to
+// the index if needed.
+func (t *tarFS) dir(name string) *tarFile {
+	f, ok := t.files[name]
+	if !ok {
+		f = &tarFile{name: name}
+		t.files[name] = f
+		parent := t.dir(path.Dir(name))
+		if parent.children == nil {
+			parent.children = make(map[string]bool)
+		}
+		parent.children[path.Base(name)] = true
+	}
+	return f
+}
+
+// lookup finds a member, following symbolic links in the folders of the
+// path, and in its last part if follow is set.
+func (t *tarFS) lookup(op, name string, follow bool) (*tarFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	f, rest := t.files["."], name
+	for hops := 0; rest != "."; {
+		elem, tail, _ := strings.Cut(rest, "/")
+		p := path.Join(f.name, elem)
+		next, ok := t.files[p]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if tail == "" {
+			tail = "."
+		}
+		if next.hdr == nil || next.hdr.Typeflag != tar.TypeSymlink || (tail == "." && !follow) {
+			f, rest = next, tail
+			continue
+		}
+		if hops++; hops > maxLinks {
+			return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many symbolic links")}
+		}
+		// Start again from the root, with the target of the link in
+		// place of the parts of the path up to it
+		target := next.hdr.Linkname
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(p), target)
+		}
+		f, rest = t.files["."], clean(path.Join(target, tail))
+	}
+	return f, nil
+}
+
+// data returns the member that holds the data of f, which differs from f
+// for hard links.
+func (t *tarFS) data(f *tarFile) *tarFile {
+	if f.hdr != nil && f.hdr.Typeflag == tar.TypeLink {
+		if d, ok := t.files[clean(f.hdr.Linkname)]; ok && d.hdr != nil {
+			return d
+		}
+	}
+	return f
+}
+
+// info returns information about an entry under the name given.
+func (t *tarFS) info(f *tarFile, name string) fs.FileInfo {
+	if f.hdr == nil {
+		return dirInfo(name)
+	}
+	info := f.hdr.FileInfo()
+	if f.hdr.Typeflag == tar.TypeLink {
+		if d := t.data(f); d != f {
+			info = d.hdr.FileInfo()
+		}
+	}
+	return namedInfo{info, name}
+}
+
+// sparse reports whether a member is stored as a sparse file, whose data
+// can't be read in place.
+func sparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns a reader for the data of a member.
+func (t *tarFS) read(f *tarFile) (*io.SectionReader, error) {
+	if !t.gzip && !sparse(f.hdr) {
+		return io.NewSectionReader(t.ra, f.offset, f.hdr.Size), nil
+	}
+	var b []byte
+	err := t.scan(func(i int, _ *tar.Header, _ int64, r io.Reader) (bool, error) {
+		if i < f.index {
+			return false, nil
+		}
+		var err error
+		b, err = readAll(r, f.hdr.Size)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))), nil
+}
+
+// walk reads the archive once, giving the members in or at name in the
+// order they are stored. Headers replaced by later ones for the same path
+// are skipped.
+func (t *tarFS) walk(name string, fn func(m Member) error) error {
+	top, err := t.lookup("walk", name, false)
+	if err != nil {
+		return err
+	}
+	if top.hdr == nil {
+		// A folder only implied by the paths of members
+		if err := fn(Member{Name: ".", Info: dirInfo(path.Base(name))}); err != nil {
+			return err
+		}
+	}
+	return t.scan(func(i int, hdr *tar.Header, _ int64, r io.Reader) (bool, error) {
+		p := clean(hdr.Name)
+		f, ok := t.files[p]
+		if !ok || f.hdr == nil || f.index != i {
+			return false, nil
+		}
+		rel, ok := relative(top.name, p)
+		if !ok {
+			return false, nil
+		}
+		m := Member{Name: rel, Info: t.info(f, path.Base(p))}
+		switch {
+		case hdr.Typeflag == tar.TypeSymlink:
+			m.Link = hdr.Linkname
+		case hdr.Typeflag == tar.TypeLink:
+			// The data is stored with an earlier member, so it is read
+			// again, as when the link is opened
+			m.Open = func() (io.ReadCloser, error) {
+				sr, err := t.read(t.data(f))
+				if err != nil {
+					return nil, err
+				}
+				return io.NopCloser(sr), nil
+			}
+		case m.Info.Mode().IsRegular():
+			m.Open = func() (io.ReadCloser, error) { return io.NopCloser(r), nil }
+		}
+		return false, fn(m)
+	})
+}
+
+func (t *tarFS) Open(name string) (fs.File, error) {
+	f, err := t.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	info := t.info(f, path.Base(name))
+	if info.IsDir() {
+		return &tarDir{info: info, entries: t.entries(f)}, nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("not a regular file")}
+	}
+	sr, err := t.read(t.data(f))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return file{sr, info}, nil
+}
+
+func (t *tarFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := t.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return t.info(f, path.Base(name)), nil
+}
+
+// Lstat returns information about a member without following a symbolic
+// link.
+func (t *tarFS) Lstat(name string) (fs.FileInfo, error) {
+	f, err := t.lookup("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return t.info(f, path.Base(name)), nil
+}
+
+// ReadLink returns the destination of a symbolic link.
+func (t *tarFS) ReadLink(name string) (string, error) {
+	f, err := t.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if f.hdr == nil || f.hdr.Typeflag != tar.TypeSymlink {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.hdr.Linkname, nil
+}
+
+func (t *tarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := t.lookup("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !t.info(f, path.Base(name)).IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return t.entries(f), nil
+}
+
+// entries returns the entries of a folder, sorted by name.
+func (t *tarFS) entries(f *tarFile) []fs.DirEntry {
+	names := make([]string, 0, len(f.children))
+	for name := range f.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]fs.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = fs.FileInfoToDirEntry(t.info(t.files[path.Join(f.name, name)], name))
+	}
+	return entries
+}
+
+// tarDir is an open folder of a tar archive.
+type tarDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	pos     int // Entries already read
+}
+
+func (d *tarDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *tarDir) Close() error {
+	return nil
+}
+
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.pos:]
+	if n <= 0 {
+		d.pos = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.pos += n
+	return rest[:n], nil
+}
+
+// namedInfo is file information under a different name, as a member's
+// header may name it differently from its cleaned path.
+type namedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (n namedInfo) Name() string {
+	return n.name
+}
+
+// dirInfo is information about a folder only implied by the paths of
+// members.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o755 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() any           { return nil }
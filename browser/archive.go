@@ -0,0 +1,180 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ancientlore/hermit2/archivefs"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/fileops"
+	"github.com/ancientlore/hermit2/jobs"
+	tea "charm.land/bubbletea/v2"
+)
+
+// openArchive opens an archive in the current folder as a new browser,
+// whose title shows the path of the archive followed by "!".
+func (m Model) openArchive(entry fs.DirEntry) (tea.Model, error) {
+	name := path.Join(strings.TrimPrefix(m.Data.Folder(), "/"), entry.Name())
+	fsys, closer, err := archivefs.Open(m.Data.FS(), name)
+	if err != nil {
+		return nil, err
+	}
+	var a Model
+	if err := a.Data.Init(fsys, m.title(entry)+"!", "/"); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	a.Header = a.Data.Title()
+	a.jobs = m.jobs
+	a.closer = closer
+	a.disk = m.osFolder()
+	return a, nil
+}
+
+// title returns the full name of an entry in the current folder, for the
+// header of a viewer.
+func (m Model) title(entry fs.DirEntry) string {
+	return filepath.Join(m.Data.Title(), entry.Name())
+}
+
+// Close closes the archive being browsed, if any.
+func (m Model) Close() error {
+	err := m.Model.Close()
+	if m.closer != nil {
+		err = errors.Join(err, m.closer.Close())
+	}
+	return err
+}
+
+// askExtract asks where to extract the selected archives, or inside an
+// archive, the selected entries.
+func (m Model) askExtract() tea.Cmd {
+	names := m.targets()
+	if len(names) == 0 {
+		return nil
+	}
+	inArchive := archivefs.IsFS(m.Data.FS())
+	if !inArchive {
+		for _, name := range names {
+			if !archivefs.IsArchive(name) {
+				return dialog.ShowError(fmt.Errorf("%s is not an archive", name))
+			}
+		}
+	}
+	// A single archive is extracted into a folder named after it
+	target := m.osFolder() + string(filepath.Separator)
+	if !inArchive && len(names) == 1 {
+		target = filepath.Join(m.osFolder(), archivefs.TrimExt(names[0]))
+	}
+	question := fmt.Sprintf("Extract %s to:", describe(names))
+	return dialog.Open(dialog.NewInput(extractID{names: names}, question, target))
+}
+
+// confirmExtract asks for confirmation of an extraction.
+func (m Model) confirmExtract(id extractID, target string) tea.Cmd {
+	target = m.resolve(target)
+	question := fmt.Sprintf("Extract %s to %s?", describe(id.names), target)
+	return dialog.Open(dialog.NewConfirm(confirmExtractID{extractID: id, target: target}, question))
+}
+
+// extract starts a job that extracts the named archives into the target
+// folder, or inside an archive, the named entries.
+func (m Model) extract(names []string, target string) tea.Cmd {
+	fsys, folder := m.Data.FS(), strings.TrimPrefix(m.Data.Folder(), "/")
+	inArchive := archivefs.IsFS(fsys)
+	release := archivefs.Hold(fsys)
+	name := fmt.Sprintf("Extract %s to %s", describe(names), target)
+	return m.startJob(name, []string{target}, func(ctx context.Context, j *jobs.Job) error {
+		defer release()
+
+		// What to extract, with whole archives opened first so that
+		// their sizes can be found
+		type source struct {
+			name string
+			fsys fs.FS
+			src  string
+		}
+		var (
+			sources []source
+			errs    []error
+		)
+		for _, name := range names {
+			p := path.Join(folder, name)
+			if inArchive {
+				sources = append(sources, source{name, fsys, p})
+				continue
+			}
+			afs, closer, err := archivefs.Open(fsys, p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			defer closer.Close()
+			sources = append(sources, source{name, afs, "."})
+		}
+
+		var (
+			bytes int64
+			files int
+		)
+		for _, s := range sources {
+			j.Current("scanning " + s.name)
+			b, f, err := fileops.SizeFS(ctx, s.fsys, s.src, nil)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				bytes += b
+				files += f
+			}
+		}
+		j.SetTotal(bytes, files)
+
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		for _, s := range sources {
+			if err := fileops.Extract(ctx, s.fsys, s.src, target, j); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// askArchive asks for the name of an archive to create from the selected
+// entries. The extension of the name chooses the type of archive.
+func (m Model) askArchive() tea.Cmd {
+	names := m.targets()
+	if len(names) == 0 {
+		return nil
+	}
+	base := "archive"
+	if len(names) == 1 {
+		base = names[0]
+	}
+	question := fmt.Sprintf("Create a zip, tar or tar.gz of %s named:", describe(names))
+	return dialog.Open(dialog.NewInput(archiveID{names: names}, question, filepath.Join(m.osFolder(), base+".zip")))
+}
+
+// createArchive starts a job that creates an archive of the named entries.
+func (m Model) createArchive(names []string, target string) tea.Cmd {
+	if strings.TrimSpace(target) == "" {
+		return nil
+	}
+	target = m.resolve(target)
+	dir := m.osFolder()
+	name := fmt.Sprintf("Archive %s to %s", describe(names), target)
+	return m.startJob(name, []string{filepath.Dir(target)}, func(ctx context.Context, j *jobs.Job) error {
+		if err := setTotals(ctx, j, dir, names); err != nil {
+			return err
+		}
+		return fileops.Archive(ctx, dir, names, target, j)
+	})
+}
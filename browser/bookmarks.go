@@ -0,0 +1,185 @@
+package browser
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/nav"
+	"github.com/ancientlore/hermit2/scroller"
+	"github.com/ancientlore/hermit2/views"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// The bookmarks are shared by all browsers, and saved in bookmarksFile
+// whenever they change.
+var (
+	bookmarks     config.Bookmarks
+	bookmarksFile string
+)
+
+// jumpSlot returns the slot a JumpBookmark key goes to, which is its place
+// in the binding, so that keys other than digits may be bound.
+func jumpSlot(k tea.KeyPressMsg) (int, bool) {
+	i := slices.Index(DefaultKeyMap.JumpBookmark.Keys(), k.String())
+	return i, i >= 0 && i < len(config.Bookmarks{})
+}
+
+// SetBookmarks sets the bookmarks and the file they are saved in. If file
+// is empty, changes are not saved.
+func SetBookmarks(b config.Bookmarks, file string) {
+	bookmarks = b
+	bookmarksFile = file
+}
+
+// saveBookmarks makes b the bookmarks and saves them.
+func saveBookmarks(b config.Bookmarks) error {
+	bookmarks = b
+	if bookmarksFile == "" {
+		return nil
+	}
+	return b.Save(bookmarksFile)
+}
+
+// jumpBookmarkMsg asks the browser to go to a bookmarked folder.
+type jumpBookmarkMsg struct {
+	slot int
+}
+
+// renameBookmarkID asks for a new name for a bookmark.
+type renameBookmarkID struct {
+	slot int
+}
+
+// setBookmark bookmarks the current folder in a slot, named after the folder.
+func (m *Model) setBookmark(slot int) tea.Cmd {
+	folder := m.osFolder()
+	b := bookmarks
+	b[slot] = config.Bookmark{Name: filepath.Base(folder), Path: folder}
+	if err := saveBookmarks(b); err != nil {
+		return dialog.ShowError(err)
+	}
+	m.Status = fmt.Sprintf("Bookmarked %s in slot %d", folder, slot)
+	return nil
+}
+
+// jumpBookmark goes to the folder bookmarked in a slot.
+func (m *Model) jumpBookmark(slot int) tea.Cmd {
+	bm := bookmarks[slot]
+	if bm.Path == "" {
+		m.Status = fmt.Sprintf("No bookmark in slot %d", slot)
+		return nil
+	}
+	folder, err := config.ExpandPath(bm.Path)
+	if err == nil {
+		err = m.chdirOS(folder)
+	}
+	if err != nil {
+		return dialog.ShowError(err)
+	}
+	return nil
+}
+
+// bookmarksModel lists the bookmark slots, so that bookmarks can be
+// renamed, deleted, reordered and gone to.
+type bookmarksModel struct {
+	scroller.Model[views.Bookmarks]
+}
+
+// NewBookmarksModel creates a new model to manage the bookmarks. Going to a
+// bookmark is done by the screen beneath it.
+func NewBookmarksModel() tea.Model {
+	return bookmarksModel{
+		Model: scroller.Model[views.Bookmarks]{
+			Header: "Bookmarks",
+			Data:   views.NewBookmarks(bookmarks),
+		},
+	}
+}
+
+func (m bookmarksModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		m.Status = ""
+		slot := m.Cursor()
+		switch {
+		case key.Matches(msg, DefaultKeyMap.Right, DefaultKeyMap.ToggleSelect):
+			return m.jump(slot)
+
+		case key.Matches(msg, DefaultKeyMap.RenameBookmark):
+			if bookmarks[slot].Path == "" {
+				m.Status = fmt.Sprintf("Slot %d is empty", slot)
+				return m, nil
+			}
+			question := fmt.Sprintf("Name of bookmark %d:", slot)
+			return m, dialog.Open(dialog.NewInput(renameBookmarkID{slot: slot}, question, bookmarks[slot].Name))
+
+		case key.Matches(msg, DefaultKeyMap.Delete):
+			return m, m.change(func(b *config.Bookmarks) { b[slot] = config.Bookmark{} })
+
+		case key.Matches(msg, DefaultKeyMap.MoveBookmarkUp):
+			return m, m.move(slot, -1)
+
+		case key.Matches(msg, DefaultKeyMap.MoveBookmarkDown):
+			return m, m.move(slot, 1)
+
+		default:
+			if i, ok := config.Slot(msg.Text); ok {
+				return m.jump(i)
+			}
+		}
+
+	case dialog.InputMsg:
+		if id, ok := msg.ID.(renameBookmarkID); ok {
+			name := strings.TrimSpace(msg.Value)
+			if name == "" {
+				return m, nil
+			}
+			return m, m.change(func(b *config.Bookmarks) { b[id.slot].Name = name })
+		}
+	}
+
+	mod, cmd := m.Model.Update(msg)
+	if scr, ok := mod.(scroller.Model[views.Bookmarks]); ok {
+		m.Model = scr
+		return m, cmd
+	}
+	return mod, cmd
+}
+
+// jump closes the list and has the screen beneath it go to a bookmark.
+func (m bookmarksModel) jump(slot int) (tea.Model, tea.Cmd) {
+	if bookmarks[slot].Path == "" {
+		m.Status = fmt.Sprintf("No bookmark in slot %d", slot)
+		return m, nil
+	}
+	return m, tea.Sequence(nav.Pop, func() tea.Msg { return jumpBookmarkMsg{slot: slot} })
+}
+
+// move swaps the bookmark in a slot with the one step slots away, keeping
+// the cursor on it.
+func (m *bookmarksModel) move(slot, step int) tea.Cmd {
+	to := slot + step
+	if to < 0 || to >= len(bookmarks) {
+		return nil
+	}
+	cmd := m.change(func(b *config.Bookmarks) { b[slot], b[to] = b[to], b[slot] })
+	m.SetCursor(to)
+	return cmd
+}
+
+// change changes the bookmarks with fn and saves them.
+func (m *bookmarksModel) change(fn func(b *config.Bookmarks)) tea.Cmd {
+	b := bookmarks
+	fn(&b)
+	err := saveBookmarks(b)
+	m.Data = views.NewBookmarks(bookmarks)
+	if err != nil {
+		return dialog.ShowError(err)
+	}
+	return nil
+}
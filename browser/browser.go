@@ -1,6 +1,8 @@
 package browser
 
 import (
+	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
@@ -9,7 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ancientlore/hermit2/archivefs"
 	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/fileops"
+	"github.com/ancientlore/hermit2/jobs"
+	"github.com/ancientlore/hermit2/nav"
 	"github.com/ancientlore/hermit2/scroller"
 	"github.com/ancientlore/hermit2/views"
 	"charm.land/bubbles/v2/key"
@@ -24,7 +31,12 @@ func refreshCmd() tea.Msg {
 
 type Model struct {
 	scroller.Model[views.FS]
-	footer string
+	jobs   *jobs.Manager // Runs long operations in the background
+	prefix bool          // Whether the command prefix key was pressed
+	mark   bool          // Whether the bookmark key was pressed
+	closer io.Closer     // Closes the archive being browsed, if any
+	disk   string        // Folder on disk that holds the archive, if any
+	other  string        // Folder of the other pane, if shown, offered for copy and move
 }
 
 func (m Model) Init() tea.Cmd {
@@ -39,66 +51,129 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Is it a key press?
 	case tea.KeyPressMsg:
+		m.Status = ""
 
-		sizeCmd := func() tea.Msg { return tea.WindowSizeMsg{Width: m.Width(), Height: m.Height()} }
+		// The key after the command prefix picks a user-defined command
+		if m.prefix {
+			m.prefix = false
+			if cmd, ok := config.Current().Commands[msg.Text]; ok {
+				return m, m.runMacro(cmd.Macro)
+			}
+			if !key.Matches(msg, scroller.DefaultKeyMap.Left) {
+				m.Status = fmt.Sprintf("No command is bound to %q", msg.String())
+			}
+			return m, nil
+		}
+
+		// The key after the bookmark key picks the slot to bookmark
+		if m.mark {
+			m.mark = false
+			if slot, ok := config.Slot(msg.Text); ok {
+				return m, m.setBookmark(slot)
+			}
+			if !key.Matches(msg, scroller.DefaultKeyMap.Left) {
+				m.Status = "Bookmark slots are 0 to 9"
+			}
+			return m, nil
+		}
 
 		// Cool, what was the actual key pressed?
 		switch {
 
+		// Commands and file operations need a folder on disk
+		case archivefs.IsFS(m.Data.FS()) && key.Matches(msg, DefaultKeyMap.RunShell, DefaultKeyMap.RunCommand,
+			DefaultKeyMap.CommandMenu, DefaultKeyMap.CommandPrefix, DefaultKeyMap.Copy, DefaultKeyMap.Move,
+			DefaultKeyMap.Delete, DefaultKeyMap.MakeDir, DefaultKeyMap.Size, DefaultKeyMap.Archive, DefaultKeyMap.NewTab,
+			DefaultKeyMap.SetBookmark, DefaultKeyMap.JumpBookmark):
+			m.Status = "Not available inside an archive"
+
+		// Links are followed to the folder or file they lead to
 		case key.Matches(msg, DefaultKeyMap.Right):
-			entry := m.Data.At(m.Cursor())
+			entry, err := m.Data.Resolved(m.Cursor())
+			if err != nil {
+				return m, dialog.ShowError(err)
+			}
 			if entry != nil {
 				if entry.IsDir() {
-					newModel, err := New(m.Data.FS(), m.Data.Root(), path.Join(m.Data.Folder(), m.Data.At(m.Cursor()).Name()))
+					err := m.chdir(m.Data.FS(), m.Data.Root(), path.Join(m.Data.Folder(), entry.Name()), "")
 					if err != nil {
-						m.footer = err.Error()
-					} else {
-						newModel.Prev = m
-						return *newModel, sizeCmd
+						return m, dialog.ShowError(err)
+					}
+				} else if archivefs.IsArchive(entry.Name()) {
+					newModel, err := m.openArchive(entry)
+					if err != nil {
+						return m, dialog.ShowError(err)
 					}
+					return m, nav.Push(newModel)
 				} else {
-					newModel, err := NewFileModel(m.Data.FS(), m.Data.Folder(), entry, m)
+					newModel, err := NewFileModel(m.Data.FS(), m.Data.Folder(), entry, m.title(entry))
 					if err == nil {
-						return newModel, sizeCmd
+						return m, nav.Push(newModel)
 					} else {
-						m.footer = err.Error()
+						return m, dialog.ShowError(err)
 					}
 				}
 			}
 
+		// Go to the parent folder, or back to the screen beneath
+		// at the top of the file system
 		case key.Matches(msg, DefaultKeyMap.Left):
-			if m.Prev != nil {
-				return m.Prev, sizeCmd
+			folder := m.Data.Folder()
+			if folder == "/" || folder == "" {
+				return m, nav.Pop
 			}
-			a, _ := path.Split(m.Data.Folder())
-			if a != "/" {
-				a = strings.TrimSuffix(a, "/")
+			err := m.chdir(m.Data.FS(), m.Data.Root(), path.Dir(folder), path.Base(folder))
+			if err != nil {
+				return m, dialog.ShowError(err)
 			}
-			if a != m.Data.Folder() {
-				newModel, err := New(m.Data.FS(), m.Data.Root(), a)
-				if err != nil {
-					log.Print(err)
-				} else {
-					return newModel, sizeCmd
-				}
+
+		// Go to the folder where the link at the cursor really leads
+		case key.Matches(msg, DefaultKeyMap.FollowLink):
+			if !m.Data.IsLink(m.Cursor()) {
+				m.Status = "Not a link"
+				break
+			}
+			p, err := m.Data.RealPath(m.Cursor())
+			if err != nil {
+				return m, dialog.ShowError(err)
+			}
+			err = m.chdir(m.Data.FS(), m.Data.Root(), path.Dir(p), path.Base(p))
+			if err != nil {
+				return m, dialog.ShowError(err)
 			}
 
 		case key.Matches(msg, DefaultKeyMap.GoHome):
-			home := config.HomeFolder()
-			home, _ = filepath.Abs(home)
-			fsRoot := filepath.VolumeName(home)
-			fsPath := strings.TrimPrefix(home, fsRoot)
-			fsRoot += string(filepath.Separator)
-			newModel, err := New(os.DirFS(fsRoot), fsRoot, filepath.ToSlash(fsPath))
-			if err != nil {
-				m.footer = err.Error()
-			} else {
-				return *newModel, sizeCmd
+			home, _ := filepath.Abs(config.HomeFolder())
+			if err := m.chdirOS(home); err != nil {
+				return m, dialog.ShowError(err)
+			}
+
+		case key.Matches(msg, DefaultKeyMap.SetBookmark):
+			m.mark = true
+			m.Status = "Bookmark slot (0-9):"
+
+		case key.Matches(msg, DefaultKeyMap.JumpBookmark):
+			if slot, ok := jumpSlot(msg); ok {
+				return m, m.jumpBookmark(slot)
 			}
 
+		case key.Matches(msg, DefaultKeyMap.Bookmarks):
+			return m, nav.Push(NewBookmarksModel())
+
 		case key.Matches(msg, DefaultKeyMap.Refresh):
 			return m, refreshCmd
 
+		case key.Matches(msg, DefaultKeyMap.Sort):
+			order, reverse := m.Data.Sort()
+			m.resort(order.Next(), reverse)
+
+		case key.Matches(msg, DefaultKeyMap.ReverseSort):
+			order, reverse := m.Data.Sort()
+			m.resort(order, !reverse)
+
+		case key.Matches(msg, DefaultKeyMap.Filter):
+			return m, m.askFilter()
+
 		// The "enter" key and the spacebar (a literal space) toggle
 		// the selected state for the item that the cursor is pointing at.
 		case key.Matches(msg, DefaultKeyMap.ToggleSelect):
@@ -127,50 +202,143 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			c := exec.Command(config.Shell())
 			c.Dir = filepath.Join(m.Data.Root(), filepath.FromSlash(m.Data.Folder()))
 			cmd := tea.ExecProcess(c, nil)
-			return m, tea.Sequence(tea.ClearScreen, cmd, refreshCmd, sizeCmd)
+			return m, tea.Sequence(tea.ClearScreen, cmd, refreshCmd)
+
+		case key.Matches(msg, DefaultKeyMap.RunCommand):
+			return m, m.askRun()
+
+		case key.Matches(msg, DefaultKeyMap.CommandMenu):
+			return m, nav.Push(NewCommandMenuModel(config.Current().Commands))
+
+		case key.Matches(msg, DefaultKeyMap.CommandPrefix):
+			m.prefix = true
+			m.Status = "Command key:"
 
 		case key.Matches(msg, DefaultKeyMap.Help):
-			newModel, err := NewHelpModel(m)
+			newModel, err := NewHelpModel()
 			if err == nil {
-				return newModel, sizeCmd
+				return m, nav.Push(newModel)
 			} else {
-				m.footer = err.Error()
+				return m, dialog.ShowError(err)
 			}
 
 		case key.Matches(msg, DefaultKeyMap.FileInfo):
 			entry := m.Data.At(m.Cursor())
 			if entry != nil {
-				newModel, err := NewFileInfoModel(m.Data.FS(), m.Data.Folder(), entry, m)
+				newModel, err := NewFileInfoModel(m.Data.FS(), m.Data.Folder(), entry, m.title(entry))
 				if err == nil {
-					return newModel, sizeCmd
+					return m, nav.Push(newModel)
 				} else {
-					m.footer = err.Error()
+					return m, dialog.ShowError(err)
 				}
 			}
 
 		case key.Matches(msg, DefaultKeyMap.ViewBinary):
-			entry := m.Data.At(m.Cursor())
+			entry, err := m.Data.Resolved(m.Cursor())
+			if err != nil {
+				return m, dialog.ShowError(err)
+			}
 			if entry != nil {
-				newModel, err := NewBinaryFileModel(m.Data.FS(), m.Data.Folder(), entry, m)
+				newModel, err := NewBinaryFileModel(m.Data.FS(), m.Data.Folder(), entry, m.title(entry))
 				if err == nil {
-					return newModel, sizeCmd
+					return m, nav.Push(newModel)
 				} else {
-					m.footer = err.Error()
+					return m, dialog.ShowError(err)
 				}
 			}
 
+		case key.Matches(msg, DefaultKeyMap.Copy):
+			return m, m.askTransfer("Copy", fileops.Copy, true)
+
+		case key.Matches(msg, DefaultKeyMap.Move):
+			return m, m.askTransfer("Move", fileops.Move, false)
+
+		case key.Matches(msg, DefaultKeyMap.Delete):
+			return m, m.askDelete()
+
+		case key.Matches(msg, DefaultKeyMap.MakeDir):
+			return m, m.askMakeDir()
+
+		case key.Matches(msg, DefaultKeyMap.Size):
+			return m, m.startSize()
+
+		case key.Matches(msg, DefaultKeyMap.Extract):
+			return m, m.askExtract()
+
+		case key.Matches(msg, DefaultKeyMap.Archive):
+			return m, m.askArchive()
+
+		case key.Matches(msg, DefaultKeyMap.Jobs):
+			return m, nav.Push(NewJobsModel(m.jobs))
+
+		case key.Matches(msg, DefaultKeyMap.CloseTab):
+			return m, nav.CloseTab
+
+		case key.Matches(msg, DefaultKeyMap.NextTab):
+			return m, nav.NextTab
+
+		case key.Matches(msg, DefaultKeyMap.PrevTab):
+			return m, nav.PrevTab
+
 		default:
 			handled = false
 		}
 
-	case refreshMsg:
-		err := m.Data.Init(m.Data.FS(), m.Data.Root(), m.Data.Folder())
-		if err != nil {
-			log.Print(err)
-		} else {
-			return m, func() tea.Msg { return tea.WindowSizeMsg{Width: m.Width(), Height: m.Height()} }
+	case dialog.InputMsg:
+		return m, m.inputDone(msg.ID, msg.Value)
+
+	case dialog.ChangeMsg:
+		if _, ok := msg.ID.(filterID); ok {
+			m.setFilter(msg.Value)
+		}
+
+	case dialog.CancelMsg:
+		if id, ok := msg.ID.(filterID); ok {
+			m.setFilter(id.initial)
 		}
 
+	case dialog.ConfirmMsg:
+		if msg.Yes {
+			return m, m.confirmed(msg.ID)
+		}
+
+	case opDoneMsg:
+		m.Status = msg.status()
+		return m, refreshCmd
+
+	case runCommandMsg:
+		return m, m.runMacro(msg.cmd.Macro)
+
+	case jumpBookmarkMsg:
+		if archivefs.IsFS(m.Data.FS()) {
+			m.Status = "Not available inside an archive"
+			break
+		}
+		return m, m.jumpBookmark(msg.slot)
+
+	case execDoneMsg:
+		if msg.err != nil {
+			return m, dialog.ShowError(fmt.Errorf("%s: %w", msg.line, msg.err))
+		}
+
+	case jobs.ProgressMsg:
+		m.Status = jobStatus(m.jobs)
+
+	case jobs.DoneMsg:
+		m.Status = msg.Job.Status(0)
+		if s := jobStatus(m.jobs); s != "" {
+			m.Status = s
+		}
+		// Every browser sees the job end, even beneath another screen,
+		// where a refreshMsg would not reach it
+		if m.changedBy(msg.Job) {
+			m.refresh()
+		}
+		return m, errorReport(msg.Job)
+
+	case refreshMsg:
+		m.refresh()
+
 	default:
 		handled = false
 	}
@@ -189,6 +357,126 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// refresh reads the folder again.
+func (m *Model) refresh() {
+	// Keep the sort, which may differ from the one remembered for the
+	// folder when another pane shows it too
+	order, reverse := m.Data.Sort()
+	var cursor string
+	if entry := m.Data.At(m.Cursor()); entry != nil {
+		cursor = entry.Name()
+	}
+	var selected []string
+	for _, entry := range m.Data.SelectedEntries() {
+		selected = append(selected, entry.Name())
+	}
+	err := m.Data.Init(m.Data.FS(), m.Data.Root(), m.Data.Folder())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	m.Data.SetSort(order, reverse)
+	m.Data.SelectNames(selected)
+	if i := m.Data.Index(cursor); i >= 0 {
+		m.SetCursor(i)
+	} else {
+		m.SetCursor(m.Cursor())
+	}
+}
+
+// changedBy returns whether a job changed the folder shown, or a folder
+// it is in.
+func (m Model) changedBy(j *jobs.Job) bool {
+	if archivefs.IsFS(m.Data.FS()) {
+		return false
+	}
+	folder := m.osFolder()
+	for _, f := range j.Folders {
+		f = filepath.Clean(f)
+		if folder == f || strings.HasPrefix(folder, strings.TrimSuffix(f, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resort sorts the listing, keeping the cursor on the same entry.
+func (m *Model) resort(order views.SortOrder, reverse bool) {
+	var name string
+	if entry := m.Data.At(m.Cursor()); entry != nil {
+		name = entry.Name()
+	}
+	m.Data.SetSort(order, reverse)
+	if i := m.Data.Index(name); i >= 0 {
+		m.SetCursor(i)
+	}
+}
+
+// askFilter asks for a filter, which is applied to the listing as it is typed.
+func (m Model) askFilter() tea.Cmd {
+	return dialog.Open(dialog.NewInput(filterID{initial: m.Data.Filter()}, "Filter (substring or glob):", m.Data.Filter()))
+}
+
+// setFilter filters the listing, keeping the cursor on the same entry
+// when it is still shown.
+func (m *Model) setFilter(filter string) {
+	if filter == m.Data.Filter() {
+		return
+	}
+	var name string
+	if entry := m.Data.At(m.Cursor()); entry != nil {
+		name = entry.Name()
+	}
+	m.Data.SetFilter(filter)
+	i := m.Data.Index(name)
+	if i < 0 {
+		i = 0
+	}
+	m.SetCursor(i)
+}
+
+// SetJobs sets the manager used to run background jobs.
+func (m *Model) SetJobs(mgr *jobs.Manager) {
+	m.jobs = mgr
+}
+
+// chdir shows another folder, with the cursor on the named entry if
+// it is there. The selection and filter are cleared.
+func (m *Model) chdir(fsys fs.FS, root, folder, name string) error {
+	var data views.FS
+	if err := data.Init(fsys, root, folder); err != nil {
+		return err
+	}
+	m.Data = data
+	m.Header = data.Title()
+	m.Status = ""
+	m.SetCursor(0)
+	m.SetCursor(max(data.Index(name), 0))
+	return nil
+}
+
+// chdirOS shows a folder on disk, given by its absolute path. The file
+// system becomes the volume that holds it.
+func (m *Model) chdirOS(folder string) error {
+	fsys, root, p := splitVolume(folder)
+	return m.chdir(fsys, root, p, "")
+}
+
+// splitVolume returns the file system of the volume that holds an absolute
+// path, its root and the path within it.
+func splitVolume(folder string) (fs.FS, string, string) {
+	root := filepath.VolumeName(folder)
+	p := strings.TrimPrefix(folder, root)
+	root += string(filepath.Separator)
+	return os.DirFS(root), root, filepath.ToSlash(p)
+}
+
+// Title returns the name of the folder shown, for the tab it is in.
+func (m Model) Title() string {
+	return filepath.Base(m.Data.Title())
+}
+
+// New creates a browser for a folder in a file system.
 func New(fsys fs.FS, root, folder string) (*Model, error) {
 	var m Model
 	err := m.Model.Data.Init(fsys, root, folder)
@@ -196,5 +484,11 @@ func New(fsys fs.FS, root, folder string) (*Model, error) {
 		return nil, err
 	}
 	m.Header = m.Data.Title()
+	m.jobs = jobs.NewManager()
 	return &m, nil
 }
+
+// Open creates a browser for a folder on disk, given by its absolute path.
+func Open(folder string) (*Model, error) {
+	return New(splitVolume(folder))
+}
@@ -0,0 +1,60 @@
+package browser
+
+import (
+	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/nav"
+	"github.com/ancientlore/hermit2/scroller"
+	"github.com/ancientlore/hermit2/views"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// runCommandMsg asks the browser to run a user-defined command.
+type runCommandMsg struct {
+	cmd config.Command
+}
+
+// commandMenuModel lists the user-defined commands so one can be chosen.
+type commandMenuModel struct {
+	scroller.Model[views.Commands]
+}
+
+// NewCommandMenuModel creates a new model to choose a user-defined command,
+// which is then run by the screen beneath it.
+func NewCommandMenuModel(cmds map[string]config.Command) tea.Model {
+	return commandMenuModel{
+		Model: scroller.Model[views.Commands]{
+			Header: "Commands",
+			Data:   views.NewCommands(cmds),
+		},
+	}
+}
+
+func (m commandMenuModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch {
+		case key.Matches(msg, DefaultKeyMap.Right, DefaultKeyMap.ToggleSelect):
+			if _, cmd, ok := m.Data.At(m.Cursor()); ok {
+				return m.run(cmd)
+			}
+			return m, nil
+
+		default:
+			if cmd, ok := m.Data.Lookup(msg.Text); ok {
+				return m.run(cmd)
+			}
+		}
+	}
+
+	mod, cmd := m.Model.Update(msg)
+	if scr, ok := mod.(scroller.Model[views.Commands]); ok {
+		m.Model = scr
+		return m, cmd
+	}
+	return mod, cmd
+}
+
+// run closes the menu and has the screen beneath it run the command.
+func (m commandMenuModel) run(cmd config.Command) (tea.Model, tea.Cmd) {
+	return m, tea.Sequence(nav.Pop, func() tea.Msg { return runCommandMsg{cmd: cmd} })
+}
@@ -0,0 +1,61 @@
+package browser
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/macro"
+	tea "charm.land/bubbletea/v2"
+)
+
+// execDoneMsg reports that a command run from the browser has ended.
+type execDoneMsg struct {
+	line string
+	err  error
+}
+
+// askRun asks for a command line, which may contain macros, to run.
+func (m Model) askRun() tea.Cmd {
+	return dialog.Open(dialog.NewInput(runID{}, "Run:", ""))
+}
+
+// runMacro expands a command macro for the entry at the cursor and the
+// selected entries, prompting for any !p values, and runs it.
+func (m Model) runMacro(s string) tea.Cmd {
+	ctx := macro.Context{
+		Dir:      m.osFolder(),
+		Selected: m.targets(),
+		Shell:    config.Shell(),
+	}
+	if entry := m.Data.At(m.Cursor()); entry != nil {
+		ctx.File = entry.Name()
+	}
+	return m.promptMacro(macro.Parse(s), ctx)
+}
+
+// promptMacro asks for each !p value in turn and then runs the macro.
+func (m Model) promptMacro(mac *macro.Macro, ctx macro.Context) tea.Cmd {
+	n := mac.Prompts()
+	if len(ctx.Prompts) < n {
+		label := fmt.Sprintf("Value %d of %d:", len(ctx.Prompts)+1, n)
+		return dialog.Open(dialog.NewInput(macroID{mac: mac, ctx: ctx}, label, ""))
+	}
+	line, err := mac.Expand(ctx)
+	if err != nil {
+		return dialog.ShowError(err)
+	}
+	return m.exec(line)
+}
+
+// exec runs a command line with the shell in the current folder and
+// refreshes the listing afterwards.
+func (m Model) exec(line string) tea.Cmd {
+	c := exec.Command(config.Shell(), config.ShellFlag(), line)
+	c.Dir = m.osFolder()
+	cmd := tea.ExecProcess(c, func(err error) tea.Msg {
+		return execDoneMsg{line: line, err: err}
+	})
+	return tea.Sequence(tea.ClearScreen, cmd, refreshCmd)
+}
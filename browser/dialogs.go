@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"strings"
+
+	"github.com/ancientlore/hermit2/macro"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Dialog IDs tell which request the result of a dialog answers.
+type (
+	// filterID asks for a filter; initial is restored if it is canceled.
+	filterID struct {
+		initial string
+	}
+
+	// runID asks for a command line to run.
+	runID struct{}
+
+	// macroID asks for the next !p value of a macro.
+	macroID struct {
+		mac *macro.Macro
+		ctx macro.Context
+	}
+
+	// makeDirID asks for the name of a new folder.
+	makeDirID struct{}
+
+	// transferID asks where to copy or move entries.
+	transferID struct {
+		op       string
+		names    []string
+		transfer transferFunc
+		scan     bool
+	}
+
+	// confirmTransferID confirms a copy or move.
+	confirmTransferID struct {
+		transferID
+		target string
+	}
+
+	// deleteID confirms deleting entries.
+	deleteID struct {
+		names []string
+	}
+
+	// extractID asks where to extract entries.
+	extractID struct {
+		names []string
+	}
+
+	// confirmExtractID confirms extracting entries.
+	confirmExtractID struct {
+		extractID
+		target string
+	}
+
+	// archiveID asks for the name of an archive to create.
+	archiveID struct {
+		names []string
+	}
+)
+
+// inputDone acts on the value accepted in an input dialog.
+func (m *Model) inputDone(id any, value string) tea.Cmd {
+	switch id := id.(type) {
+	case filterID:
+		m.setFilter(value)
+	case runID:
+		if strings.TrimSpace(value) != "" {
+			return m.runMacro(value)
+		}
+	case macroID:
+		ctx := id.ctx
+		ctx.Prompts = append(ctx.Prompts[:len(ctx.Prompts):len(ctx.Prompts)], value)
+		return m.promptMacro(id.mac, ctx)
+	case makeDirID:
+		return m.makeDir(value)
+	case transferID:
+		return m.confirmTransfer(id, value)
+	case extractID:
+		return m.confirmExtract(id, value)
+	case archiveID:
+		return m.createArchive(id.names, value)
+	}
+	return nil
+}
+
+// confirmed acts on a request that was confirmed.
+func (m *Model) confirmed(id any) tea.Cmd {
+	switch id := id.(type) {
+	case confirmTransferID:
+		return m.transfer(id.op, id.names, id.target, id.transfer, id.scan)
+	case deleteID:
+		return m.delete(id.names)
+	case confirmExtractID:
+		return m.extract(id.names, id.target)
+	}
+	return nil
+}
@@ -19,7 +19,9 @@ import (
 	tea "charm.land/bubbletea/v2"
 )
 
-func NewFileModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Model) (tea.Model, error) {
+// NewFileModel creates a new model to view a file as text or bytes,
+// depending on its contents. The title names the file in the header.
+func NewFileModel(fs fs.FS, folder string, entry fs.DirEntry, title string) (tea.Model, error) {
 	if entry.Type().IsRegular() {
 		f, err := fs.Open(path.Join(strings.TrimPrefix(folder, "/"), entry.Name()))
 		if err != nil {
@@ -48,11 +50,17 @@ func NewFileModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Model) (t
 		}
 
 		if isText {
-			m, err := NewTextModel(rdr, path.Join(folder, entry.Name()), prev)
+			// Files that can be read at any offset are indexed in the background
+			if ra, ok := f.(io.ReaderAt); ok {
+				if info, err := f.Stat(); err == nil {
+					return newTextModel(views.OpenText(ra, info.Size(), f, title), title), nil
+				}
+			}
+			m, err := NewTextModel(rdr, title)
 			f.Close()
 			return m, err
 		} else if rs, ok := rdr.(io.ReadSeekCloser); ok {
-			m, err := NewBinaryModel(rs, path.Join(folder, entry.Name()), prev)
+			m, err := NewBinaryModel(rs, title)
 			if err != nil {
 				f.Close()
 				// otherwise Viewer owns the file
@@ -65,15 +73,16 @@ func NewFileModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Model) (t
 	return nil, fmt.Errorf("not a viewable file")
 }
 
-// NewBinaryFileModel creates a new model to view a file as bytes.
-func NewBinaryFileModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Model) (tea.Model, error) {
+// NewBinaryFileModel creates a new model to view a file as bytes. The
+// title names the file in the header.
+func NewBinaryFileModel(fs fs.FS, folder string, entry fs.DirEntry, title string) (tea.Model, error) {
 	if entry.Type().IsRegular() {
 		f, err := fs.Open(path.Join(strings.TrimPrefix(folder, "/"), entry.Name()))
 		if err != nil {
 			return nil, err
 		}
 		if rs, ok := f.(io.ReadSeekCloser); ok {
-			return NewBinaryModel(rs, path.Join(folder, entry.Name()), prev)
+			return NewBinaryModel(rs, title)
 		}
 		f.Close()
 	}
@@ -81,21 +90,17 @@ func NewBinaryFileModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Mod
 }
 
 // NewTextModel creates a new model to view a text file.
-func NewTextModel(rdr io.Reader, path string, prev tea.Model) (tea.Model, error) {
+func NewTextModel(rdr io.Reader, path string) (tea.Model, error) {
 	b, err := io.ReadAll(rdr)
 	if err != nil {
 		return nil, err
 	}
 
-	return scroller.Model[views.Text]{
-		Header: path,
-		Data:   views.NewText(string(b), path),
-		Prev:   prev,
-	}, nil
+	return newTextModel(views.NewText(string(b), path), path), nil
 }
 
 // NewBinaryModel creates a new model to view a binary file.
-func NewBinaryModel(rdr io.ReadSeekCloser, path string, prev tea.Model) (tea.Model, error) {
+func NewBinaryModel(rdr io.ReadSeekCloser, path string) (tea.Model, error) {
 	b, err := views.NewBinary(rdr)
 	if err != nil {
 		return nil, err
@@ -103,7 +108,6 @@ func NewBinaryModel(rdr io.ReadSeekCloser, path string, prev tea.Model) (tea.Mod
 	return scroller.Model[views.Binary]{
 		Header: path,
 		Data:   *b,
-		Prev:   prev,
 	}, nil
 }
 
@@ -185,8 +189,9 @@ var templates = template.Must(
 	}).ParseFS(templateFs, "*.txt"),
 )
 
-// NewFileInfoModel creates a new model to view file information.
-func NewFileInfoModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Model) (tea.Model, error) {
+// NewFileInfoModel creates a new model to view file information. The
+// title names the file in the header.
+func NewFileInfoModel(fs fs.FS, folder string, entry fs.DirEntry, title string) (tea.Model, error) {
 	var wtr bytes.Buffer
 	err := templates.ExecuteTemplate(&wtr, "fileinfo.txt", entry)
 	if err != nil {
@@ -194,19 +199,21 @@ func NewFileInfoModel(fs fs.FS, folder string, entry fs.DirEntry, prev tea.Model
 		return nil, err
 	}
 	rdr := bytes.NewReader(wtr.Bytes())
-	return NewTextModel(rdr, path.Join(folder, entry.Name()), prev)
+	return NewTextModel(rdr, title)
 }
 
 type helpInfo struct {
 	ScrollKeys  *scroller.KeyMap
+	ViewKeys    *scroller.ViewKeyMap
 	BrowserKeys *KeyMap
 }
 
 // NewHelpMode creates a new model to view help text.
-func NewHelpModel(prev tea.Model) (tea.Model, error) {
+func NewHelpModel() (tea.Model, error) {
 	var wtr bytes.Buffer
 	h := &helpInfo{
 		ScrollKeys:  &scroller.DefaultKeyMap,
+		ViewKeys:    &scroller.DefaultViewKeyMap,
 		BrowserKeys: &DefaultKeyMap,
 	}
 
@@ -216,5 +223,5 @@ func NewHelpModel(prev tea.Model) (tea.Model, error) {
 		return nil, err
 	}
 	rdr := bytes.NewReader(wtr.Bytes())
-	return NewTextModel(rdr, "HERMIT Help", prev)
+	return NewTextModel(rdr, "HERMIT Help")
 }
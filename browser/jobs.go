@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/jobs"
+	"github.com/ancientlore/hermit2/nav"
+	"github.com/ancientlore/hermit2/scroller"
+	"github.com/ancientlore/hermit2/views"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// jobsModel shows the list of background jobs and lets the user cancel them.
+type jobsModel struct {
+	scroller.Model[views.Jobs]
+}
+
+// NewJobsModel creates a new model to view background jobs.
+func NewJobsModel(mgr *jobs.Manager) tea.Model {
+	return jobsModel{
+		Model: scroller.Model[views.Jobs]{
+			Header: "Jobs",
+			Data:   views.NewJobs(mgr),
+		},
+	}
+}
+
+func (m jobsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch {
+		case key.Matches(msg, DefaultKeyMap.CancelJob):
+			if j := m.Data.At(m.Cursor()); j != nil {
+				j.Cancel()
+			}
+			return m, nil
+
+		case key.Matches(msg, scroller.DefaultKeyMap.Left):
+			// Folders may have changed while jobs ran
+			return m, tea.Sequence(nav.Pop, refreshCmd)
+		}
+	}
+
+	mod, cmd := m.Model.Update(msg)
+	if scr, ok := mod.(scroller.Model[views.Jobs]); ok {
+		m.Model = scr
+		return m, cmd
+	}
+	return mod, cmd
+}
+
+// jobStatus returns the footer status for the running jobs.
+func jobStatus(mgr *jobs.Manager) string {
+	running := mgr.Running()
+	if len(running) == 0 {
+		return ""
+	}
+	s := running[0].Status(20)
+	if len(running) > 1 {
+		s = fmt.Sprintf("(%d jobs) %s", len(running), s)
+	}
+	return s
+}
+
+// errorReport returns a command that shows the individual errors of a job
+// that has ended, if there is more than one. Every browser sees a job end,
+// and only the first to do so shows them.
+func errorReport(j *jobs.Job) tea.Cmd {
+	err := j.Err()
+	if err == nil {
+		return nil
+	}
+	var errs []error
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = u.Unwrap()
+	}
+	if len(errs) < 2 || !j.MarkReported() {
+		return nil
+	}
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = strings.ReplaceAll(e.Error(), "\n", " ")
+	}
+	// The report opens over whatever screen is shown, which need not be
+	// a browser
+	report, err := NewTextModel(strings.NewReader(strings.Join(lines, "\n")), j.Name+": errors")
+	if err != nil {
+		return dialog.ShowError(err)
+	}
+	return nav.Push(report)
+}
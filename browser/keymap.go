@@ -1,21 +1,61 @@
 package browser
 
-import "charm.land/bubbles/v2/key"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
+	"charm.land/bubbles/v2/key"
+	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/scroller"
+)
+
+// KeyMap holds the key bindings of the folder browser.
 type KeyMap struct {
-	Left         key.Binding
-	Right        key.Binding
-	ToggleSelect key.Binding
-	Select       key.Binding
-	DeSelect     key.Binding
-	SelectAll    key.Binding
-	DeSelectAll  key.Binding
-	RunShell     key.Binding
-	GoHome       key.Binding
-	Refresh      key.Binding
-	Help         key.Binding
-	ViewBinary   key.Binding
-	FileInfo     key.Binding
+	Left          key.Binding
+	Right         key.Binding
+	ToggleSelect  key.Binding
+	Select        key.Binding
+	DeSelect      key.Binding
+	SelectAll     key.Binding
+	DeSelectAll   key.Binding
+	RunShell      key.Binding
+	RunCommand    key.Binding
+	CommandMenu   key.Binding
+	CommandPrefix key.Binding
+	GoHome        key.Binding
+	FollowLink    key.Binding
+	SetBookmark   key.Binding
+	JumpBookmark  key.Binding
+	Bookmarks     key.Binding
+	Refresh       key.Binding
+	Help          key.Binding
+	ViewBinary    key.Binding
+	FileInfo      key.Binding
+	SwitchPane    key.Binding
+	TogglePanes   key.Binding
+	NewTab        key.Binding
+	CloseTab      key.Binding
+	NextTab       key.Binding
+	PrevTab       key.Binding
+	Sort          key.Binding
+	ReverseSort   key.Binding
+	Filter        key.Binding
+	Copy          key.Binding
+	Move          key.Binding
+	Delete        key.Binding
+	MakeDir       key.Binding
+	Size          key.Binding
+	Extract       key.Binding
+	Archive       key.Binding
+	Jobs          key.Binding
+	CancelJob     key.Binding
+
+	RenameBookmark   key.Binding
+	MoveBookmarkUp   key.Binding
+	MoveBookmarkDown key.Binding
 }
 
 var DefaultKeyMap = KeyMap{
@@ -51,16 +91,44 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("$"),
 		key.WithHelp("$", "run shell"),
 	),
+	RunCommand: key.NewBinding(
+		key.WithKeys("!"),
+		key.WithHelp("!", "run a command line (!f, !m, !q, !d, !p macros)"),
+	),
+	CommandMenu: key.NewBinding(
+		key.WithKeys("f2"),
+		key.WithHelp("f2", "choose a command from the command menu"),
+	),
+	CommandPrefix: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "run a command; follow with the command key"),
+	),
 	GoHome: key.NewBinding(
 		key.WithKeys("~", "alt+h", "ctrl+h"),
 		key.WithHelp("~/alt+h/ctrl+h", "navigate to home folder"),
 	),
+	FollowLink: key.NewBinding(
+		key.WithKeys("L"),
+		key.WithHelp("L", "go to where the link at the cursor really leads"),
+	),
+	SetBookmark: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "bookmark the folder; follow with a slot from 0 to 9"),
+	),
+	JumpBookmark: key.NewBinding(
+		key.WithKeys("0", "1", "2", "3", "4", "5", "6", "7", "8", "9"),
+		key.WithHelp("0-9", "go to the folder bookmarked in a slot"),
+	),
+	Bookmarks: key.NewBinding(
+		key.WithKeys("B"),
+		key.WithHelp("B", "list, rename, delete and reorder bookmarks"),
+	),
 	Refresh: key.NewBinding(
 		key.WithKeys("alt+r", "ctrl+r", "f5"),
 		key.WithHelp("alt+r/ctrl+r/f5", "refresh directory listing"),
 	),
 	Help: key.NewBinding(
-		key.WithKeys("alt+h", "ctrl+h", "?"),
+		key.WithKeys("?"),
 		key.WithHelp("?", "show help"),
 	),
 	ViewBinary: key.NewBinding(
@@ -71,4 +139,206 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("tab"),
 		key.WithHelp("tab", "view file information"),
 	),
+	SwitchPane: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "switch to the other pane"),
+	),
+	TogglePanes: key.NewBinding(
+		key.WithKeys("|"),
+		key.WithHelp("|", "show two panes or one"),
+	),
+	NewTab: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "open the folder at the cursor in a new tab"),
+	),
+	CloseTab: key.NewBinding(
+		key.WithKeys("ctrl+w"),
+		key.WithHelp("ctrl+w", "close the tab"),
+	),
+	NextTab: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "show the next tab"),
+	),
+	PrevTab: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "show the previous tab"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "cycle sort order (name, ext, size, date)"),
+	),
+	ReverseSort: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "reverse sort order"),
+	),
+	Filter: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "filter entries by substring or glob"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy selected entries"),
+	),
+	Move: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "move or rename selected entries"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("d", "delete"),
+		key.WithHelp("d/delete", "delete selected entries"),
+	),
+	MakeDir: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "make a new folder"),
+	),
+	Size: key.NewBinding(
+		key.WithKeys("="),
+		key.WithHelp("=", "total the size of selected entries"),
+	),
+	Extract: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "extract selected archives or archive entries"),
+	),
+	Archive: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "create an archive of selected entries"),
+	),
+	Jobs: key.NewBinding(
+		key.WithKeys("j"),
+		key.WithHelp("j", "view background jobs"),
+	),
+	CancelJob: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "cancel the job at the cursor (in the job list)"),
+	),
+	RenameBookmark: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "rename the bookmark at the cursor (in the bookmark list)"),
+	),
+	MoveBookmarkUp: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "move the bookmark at the cursor up a slot (in the bookmark list)"),
+	),
+	MoveBookmarkDown: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "move the bookmark at the cursor down a slot (in the bookmark list)"),
+	),
+}
+
+// Bindings returns the bindings in the key map by action name.
+func (km *KeyMap) Bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"Left":          &km.Left,
+		"Right":         &km.Right,
+		"ToggleSelect":  &km.ToggleSelect,
+		"Select":        &km.Select,
+		"DeSelect":      &km.DeSelect,
+		"SelectAll":     &km.SelectAll,
+		"DeSelectAll":   &km.DeSelectAll,
+		"RunShell":      &km.RunShell,
+		"RunCommand":    &km.RunCommand,
+		"CommandMenu":   &km.CommandMenu,
+		"CommandPrefix": &km.CommandPrefix,
+		"GoHome":        &km.GoHome,
+		"FollowLink":    &km.FollowLink,
+		"SetBookmark":   &km.SetBookmark,
+		"JumpBookmark":  &km.JumpBookmark,
+		"Bookmarks":     &km.Bookmarks,
+		"Refresh":       &km.Refresh,
+		"Help":          &km.Help,
+		"ViewBinary":    &km.ViewBinary,
+		"FileInfo":      &km.FileInfo,
+		"SwitchPane":    &km.SwitchPane,
+		"TogglePanes":   &km.TogglePanes,
+		"NewTab":        &km.NewTab,
+		"CloseTab":      &km.CloseTab,
+		"NextTab":       &km.NextTab,
+		"PrevTab":       &km.PrevTab,
+		"Sort":          &km.Sort,
+		"ReverseSort":   &km.ReverseSort,
+		"Filter":        &km.Filter,
+		"Copy":          &km.Copy,
+		"Move":          &km.Move,
+		"Delete":        &km.Delete,
+		"MakeDir":       &km.MakeDir,
+		"Size":          &km.Size,
+		"Extract":       &km.Extract,
+		"Archive":       &km.Archive,
+		"Jobs":          &km.Jobs,
+		"CancelJob":     &km.CancelJob,
+
+		"RenameBookmark":   &km.RenameBookmark,
+		"MoveBookmarkUp":   &km.MoveBookmarkUp,
+		"MoveBookmarkDown": &km.MoveBookmarkDown,
+	}
+}
+
+// ApplyKeys applies the [keys] section of the configuration to the browser
+// and scroller key maps. Action names are matched without regard to case.
+// It reports unknown actions and keys that are bound to more than one action.
+// The browser and the file viewers are never active together, so their keys
+// are only compared with each other's through the common scroller keys.
+func ApplyKeys(cfg *config.Config) error {
+	var (
+		browserKeys = DefaultKeyMap.Bindings()
+		scrollKeys  = scroller.DefaultKeyMap.Bindings()
+		viewKeys    = scroller.DefaultViewKeyMap.Bindings()
+	)
+	keyMaps := []map[string]*key.Binding{browserKeys, scrollKeys, viewKeys}
+	var errs []error
+
+	configured := make(map[string]string) // action -> name used in the file
+	for _, name := range slices.Sorted(maps.Keys(cfg.Keys)) {
+		found := false
+		for _, m := range keyMaps {
+			for action, b := range m {
+				if strings.EqualFold(action, name) {
+					scroller.Rebind(b, cfg.Keys[name])
+					configured[action] = name
+					found = true
+				}
+			}
+		}
+		if !found {
+			errs = append(errs, cfg.ErrorAt(fmt.Sprintf("unknown key action %q", name), "keys", name))
+		}
+	}
+
+	// Find keys bound to more than one action. Left appears in both the
+	// browser and scroller maps with the same meaning, so actions are
+	// compared by name. A clash among the scroller keys is reported once.
+	reported := make(map[string]bool)
+	for _, scope := range [][]map[string]*key.Binding{{browserKeys, scrollKeys}, {viewKeys, scrollKeys}} {
+		owners := make(map[string][]string)
+		for _, m := range scope {
+			for _, action := range slices.Sorted(maps.Keys(m)) {
+				for _, k := range m[action].Keys() {
+					if !slices.Contains(owners[k], action) {
+						owners[k] = append(owners[k], action)
+					}
+				}
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(owners)) {
+			actions := owners[k]
+			if len(actions) < 2 {
+				continue
+			}
+			slices.Sort(actions)
+			msg := fmt.Sprintf("key %q is bound to more than one action: %s", k, strings.Join(actions, ", "))
+			if reported[msg] {
+				continue
+			}
+			reported[msg] = true
+			pos := []string{"keys"}
+			for _, action := range actions {
+				if name, ok := configured[action]; ok {
+					pos = append(pos, name)
+					break
+				}
+			}
+			errs = append(errs, cfg.ErrorAt(msg, pos...))
+		}
+	}
+	return errors.Join(errs...)
 }
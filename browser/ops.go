@@ -0,0 +1,240 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ancientlore/hermit2/archivefs"
+	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/fileops"
+	"github.com/ancientlore/hermit2/jobs"
+	tea "charm.land/bubbletea/v2"
+)
+
+// opDoneMsg reports the result of a file operation.
+type opDoneMsg struct {
+	op    string  // Name of the operation
+	total int     // Number of items the operation was applied to
+	errs  []error // Errors for the items that failed
+}
+
+// targets returns the names of the selected entries, or the entry
+// at the cursor if nothing is selected. Selected entries hidden by the
+// filter are included.
+func (m Model) targets() []string {
+	var names []string
+	for _, entry := range m.Data.SelectedEntries() {
+		names = append(names, entry.Name())
+	}
+	if len(names) == 0 {
+		if entry := m.Data.At(m.Cursor()); entry != nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// describe returns a short description of the named entries for prompts.
+func describe(names []string) string {
+	if len(names) == 1 {
+		return fmt.Sprintf("%q", names[0])
+	}
+	return fmt.Sprintf("%d items", len(names))
+}
+
+// osFolder returns the operating system path of the current folder, or
+// inside an archive, of the folder that holds the archive.
+func (m Model) osFolder() string {
+	if archivefs.IsFS(m.Data.FS()) {
+		return m.disk
+	}
+	return filepath.Join(m.Data.Root(), filepath.FromSlash(m.Data.Folder()))
+}
+
+// resolve converts a path typed by the user into an absolute path,
+// relative to the current folder.
+func (m Model) resolve(p string) string {
+	p = strings.TrimSpace(p)
+	if strings.HasPrefix(p, "~") {
+		if abs, err := config.ExpandPath(p); err == nil {
+			return abs
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(m.osFolder(), p)
+	}
+	return filepath.Clean(p)
+}
+
+// transferFunc copies or moves a file or folder.
+type transferFunc func(ctx context.Context, src, dst string, r fileops.Reporter) error
+
+// askTransfer asks for the destination of a copy or move and then
+// for confirmation. If scan is true, the size of the entries is
+// found first so that progress can be shown.
+func (m Model) askTransfer(op string, transfer transferFunc, scan bool) tea.Cmd {
+	names := m.targets()
+	if len(names) == 0 {
+		return nil
+	}
+	id := transferID{op: op, names: names, transfer: transfer, scan: scan}
+	question := fmt.Sprintf("%s %s to:", op, describe(names))
+	target := m.osFolder()
+	if m.other != "" {
+		target = m.other
+	}
+	return dialog.Open(dialog.NewInput(id, question, target+string(filepath.Separator)))
+}
+
+// confirmTransfer asks for confirmation of a copy or move.
+func (m Model) confirmTransfer(id transferID, target string) tea.Cmd {
+	target = m.resolve(target)
+	question := fmt.Sprintf("%s %s to %s?", id.op, describe(id.names), target)
+	return dialog.Open(dialog.NewConfirm(confirmTransferID{transferID: id, target: target}, question))
+}
+
+// transfer starts a job that copies or moves the named entries. If target
+// is an existing folder, the entries are placed inside it; otherwise a single
+// entry is given the target name.
+func (m Model) transfer(op string, names []string, target string, transfer transferFunc, scan bool) tea.Cmd {
+	dir := m.osFolder()
+	name := fmt.Sprintf("%s %s to %s", op, describe(names), target)
+	// A move changes the folder it takes the entries from, too
+	changed := target
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		changed = filepath.Dir(target)
+	}
+	return m.startJob(name, []string{dir, changed}, func(ctx context.Context, j *jobs.Job) error {
+		info, err := os.Stat(target)
+		isDir := err == nil && info.IsDir()
+		if !isDir && len(names) > 1 {
+			return fmt.Errorf("%s is not a folder", target)
+		}
+		if scan {
+			if err := setTotals(ctx, j, dir, names); err != nil {
+				return err
+			}
+		}
+		var errs []error
+		for _, name := range names {
+			dst := target
+			if isDir {
+				dst = filepath.Join(target, name)
+			}
+			if err := transfer(ctx, filepath.Join(dir, name), dst, j); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// startJob starts a background job that changes the given folders and
+// returns a command that shows its progress.
+func (m Model) startJob(name string, folders []string, f jobs.Func) tea.Cmd {
+	j := m.jobs.Start(name, folders, f)
+	return func() tea.Msg {
+		return jobs.ProgressMsg{Job: j}
+	}
+}
+
+// setTotals scans the named entries to find the amount of work to do.
+func setTotals(ctx context.Context, j *jobs.Job, dir string, names []string) error {
+	var (
+		bytes int64
+		files int
+	)
+	for _, name := range names {
+		j.Current("scanning " + name)
+		b, f, err := fileops.Size(ctx, filepath.Join(dir, name), nil)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			bytes += b
+			files += f
+		}
+	}
+	j.SetTotal(bytes, files)
+	return nil
+}
+
+// askDelete asks for confirmation to delete the selected entries.
+func (m Model) askDelete() tea.Cmd {
+	names := m.targets()
+	if len(names) == 0 {
+		return nil
+	}
+	question := fmt.Sprintf("Delete %s, including folder contents?", describe(names))
+	return dialog.Open(dialog.NewConfirm(deleteID{names: names}, question))
+}
+
+// delete starts a job to delete the named entries.
+func (m Model) delete(names []string) tea.Cmd {
+	dir := m.osFolder()
+	return m.startJob("Delete "+describe(names), []string{dir}, func(ctx context.Context, j *jobs.Job) error {
+		if err := setTotals(ctx, j, dir, names); err != nil {
+			return err
+		}
+		j.SetTotal(0, j.Progress().TotalFiles)
+		var errs []error
+		for _, name := range names {
+			if err := fileops.Delete(ctx, filepath.Join(dir, name), j); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// startSize starts a job that totals the size of the selected entries.
+func (m Model) startSize() tea.Cmd {
+	names := m.targets()
+	if len(names) == 0 {
+		return nil
+	}
+	dir := m.osFolder()
+	return m.startJob("Size of "+describe(names), nil, func(ctx context.Context, j *jobs.Job) error {
+		var errs []error
+		for _, name := range names {
+			if _, _, err := fileops.Size(ctx, filepath.Join(dir, name), j); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// askMakeDir asks for the name of a new folder.
+func (m Model) askMakeDir() tea.Cmd {
+	return dialog.Open(dialog.NewInput(makeDirID{}, "New folder:", ""))
+}
+
+// makeDir creates a folder.
+func (m Model) makeDir(name string) tea.Cmd {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	p := m.resolve(name)
+	return func() tea.Msg {
+		var errs []error
+		if err := fileops.MakeDir(p); err != nil {
+			errs = append(errs, err)
+		}
+		return opDoneMsg{op: "Make folder", total: 1, errs: errs}
+	}
+}
+
+// status summarizes the result of an operation for the footer.
+func (msg opDoneMsg) status() string {
+	s := fmt.Sprintf("%s: %d of %d done", msg.op, msg.total-len(msg.errs), msg.total)
+	if len(msg.errs) > 0 {
+		s += fmt.Sprintf(", failed: %v", errors.Join(msg.errs...))
+	}
+	return s
+}
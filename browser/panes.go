@@ -0,0 +1,240 @@
+package browser
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/jobs"
+	"github.com/ancientlore/hermit2/nav"
+	"github.com/ancientlore/hermit2/theme"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// separator is the style of the line between the panes.
+var separator = theme.Default().Text
+
+// SetTheme sets the style of the line between the panes.
+func SetTheme(t theme.Theme) {
+	separator = t.Text
+}
+
+// Panes shows two browsers side by side, in the manner of Norton Commander.
+// Keys go to the active pane, and copy and move offer the folder of the
+// other pane as the target. Each pane keeps its own folder, sort, filter
+// and selection. The panes can be collapsed to show only the active one.
+type Panes struct {
+	panes  [2]Model
+	active int               // The pane with the focus
+	split  bool              // Whether both panes are shown
+	size   tea.WindowSizeMsg // The last window size
+}
+
+// NewPanes creates a two-pane screen from a browser. The second pane starts
+// in the same folder and shares its jobs. If split is false, only the
+// active pane is shown until the panes are toggled.
+func NewPanes(m Model, split bool) (Panes, error) {
+	second, err := New(m.Data.FS(), m.Data.Root(), m.Data.Folder())
+	if err != nil {
+		return Panes{}, err
+	}
+	second.jobs = m.jobs
+	return Panes{panes: [2]Model{m, *second}, split: split}, nil
+}
+
+func (p Panes) Init() tea.Cmd {
+	return nil
+}
+
+func (p Panes) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.size = msg
+		return p, p.resize()
+
+	case tea.KeyPressMsg:
+		// A key after the command prefix or bookmark key belongs to the pane
+		if p.panes[p.active].prefix || p.panes[p.active].mark {
+			break
+		}
+		switch {
+		case key.Matches(msg, DefaultKeyMap.SwitchPane):
+			if p.split {
+				p.active = 1 - p.active
+				p.blur()
+			}
+			return p, nil
+
+		case key.Matches(msg, DefaultKeyMap.TogglePanes):
+			p.split = !p.split
+			return p, p.resize()
+
+		case key.Matches(msg, DefaultKeyMap.NewTab):
+			tab, err := p.newTab()
+			if err != nil {
+				return p, dialog.ShowError(err)
+			}
+			return p, nav.NewTab(tab)
+		}
+
+	case refreshMsg, jobs.ProgressMsg, jobs.DoneMsg:
+		// Either folder may have changed, and both panes show the jobs
+		var cmds [2]tea.Cmd
+		for i := range p.panes {
+			p, cmds[i] = p.updatePane(i, msg)
+		}
+		return p, tea.Batch(cmds[:]...)
+	}
+
+	return p.updatePane(p.active, msg)
+}
+
+// newTab returns panes for a new tab, showing the folder at the cursor of
+// the active pane, or else its folder.
+func (p Panes) newTab() (Panes, error) {
+	m := p.panes[p.active]
+	folder := m.Data.Folder()
+	entry, err := m.Data.Resolved(m.Cursor())
+	if err != nil {
+		return Panes{}, err
+	}
+	if entry != nil && entry.IsDir() {
+		folder = path.Join(folder, entry.Name())
+	}
+	first, err := New(m.Data.FS(), m.Data.Root(), folder)
+	if err != nil {
+		return Panes{}, err
+	}
+	first.jobs = m.jobs
+	return NewPanes(*first, p.split)
+}
+
+// Title returns the name of the folder in the active pane, for its tab.
+func (p Panes) Title() string {
+	return p.panes[p.active].Title()
+}
+
+// Tab returns the folders of the panes, to be saved in a session.
+func (p Panes) Tab() config.Tab {
+	return config.Tab{
+		Folders: []string{p.panes[0].osFolder(), p.panes[1].osFolder()},
+		Active:  p.active,
+		Split:   p.split,
+	}
+}
+
+// OpenTab creates panes that show the folders of a tab saved in a session,
+// running jobs with mgr. A pane whose folder can't be opened shows the
+// folder of the other one.
+func OpenTab(tab config.Tab, mgr *jobs.Manager) (Panes, error) {
+	var (
+		panes [2]*Model
+		errs  []error
+	)
+	for i := range panes {
+		if i >= len(tab.Folders) {
+			break
+		}
+		m, err := Open(tab.Folders[i])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.SetJobs(mgr)
+		panes[i] = m
+	}
+	switch {
+	case panes[0] == nil && panes[1] == nil:
+		if len(errs) == 0 {
+			errs = append(errs, errors.New("tab has no folders"))
+		}
+		return Panes{}, errors.Join(errs...)
+	case panes[0] == nil:
+		panes[0], panes[1] = panes[1], nil
+	}
+	p, err := NewPanes(*panes[0], tab.Split)
+	if err != nil {
+		return Panes{}, err
+	}
+	if panes[1] != nil {
+		p.panes[1] = *panes[1]
+	}
+	if tab.Active == 1 {
+		p.active = 1
+	}
+	return p, nil
+}
+
+// updatePane passes a message to pane i.
+func (p Panes) updatePane(i int, msg tea.Msg) (Panes, tea.Cmd) {
+	p.panes[i].other = ""
+	if p.split {
+		p.panes[i].other = p.panes[1-i].osFolder()
+	}
+	mod, cmd := p.panes[i].Update(msg)
+	if b, ok := mod.(Model); ok {
+		p.panes[i] = b
+	}
+	return p, cmd
+}
+
+// blur hides the cursor of the inactive pane when both are shown.
+func (p *Panes) blur() {
+	for i := range p.panes {
+		p.panes[i].Blur = p.split && i != p.active
+	}
+}
+
+// resize divides the width of the window between the panes shown.
+func (p *Panes) resize() tea.Cmd {
+	p.blur()
+	if p.size.Width == 0 {
+		return nil
+	}
+	var cmds []tea.Cmd
+	widths := [2]int{p.size.Width, p.size.Width}
+	if p.split {
+		// One column is left for the separator
+		widths[0] = (p.size.Width - 1) / 2
+		widths[1] = p.size.Width - 1 - widths[0]
+	}
+	for i := range p.panes {
+		if !p.split && i != p.active {
+			continue
+		}
+		size := tea.WindowSizeMsg{Width: widths[i], Height: p.size.Height}
+		var cmd tea.Cmd
+		*p, cmd = p.updatePane(i, size)
+		cmds = append(cmds, cmd)
+	}
+	return tea.Batch(cmds...)
+}
+
+// View renders the panes shown, with a line between them.
+func (p Panes) View() tea.View {
+	if !p.split {
+		return p.panes[p.active].View()
+	}
+	lines := make([]string, max(p.size.Height, 1))
+	for i := range lines {
+		lines[i] = separator.Render("│")
+	}
+	// Lines too long for a narrow pane would wrap and push the other down
+	var content [2]string
+	for i := range p.panes {
+		clip := lipgloss.NewStyle().MaxWidth(p.panes[i].Width()).MaxHeight(len(lines))
+		content[i] = clip.Render(p.panes[i].View().Content)
+	}
+	v := tea.NewView(lipgloss.JoinHorizontal(lipgloss.Top, content[0], strings.Join(lines, "\n"), content[1]))
+	v.AltScreen = true
+	return v
+}
+
+// Close closes both panes.
+func (p Panes) Close() error {
+	return errors.Join(p.panes[0].Close(), p.panes[1].Close())
+}
@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"time"
+
+	"github.com/ancientlore/hermit2/scroller"
+	"github.com/ancientlore/hermit2/views"
+	tea "charm.land/bubbletea/v2"
+)
+
+// indexTickMsg asks a text model to check how indexing or wrapping of its
+// file is going.
+// It goes to every screen, since the model may be beneath another, and only
+// the model with the same id acts on it.
+type indexTickMsg struct {
+	id int
+}
+
+// Broadcast marks indexTickMsg as a message for every screen.
+func (indexTickMsg) Broadcast() {}
+
+// lastTextID is the id of the text model created last.
+var lastTextID int
+
+// indexTick returns a command that sends an indexTickMsg for a text model
+// after a short wait.
+func indexTick(id int) tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+		return indexTickMsg{id: id}
+	})
+}
+
+// textModel views text, updating the view while a large file is indexed
+// or its lines are wrapped.
+type textModel struct {
+	scroller.Model[views.Text]
+	id      int  // Tells the ticks of this model from those of others
+	ticking bool // Whether a tick is on its way
+	indexed int  // Lines indexed at the last tick
+	length  int  // Length of the view at the last tick
+	line    int  // Line at the cursor while lines are being wrapped
+}
+
+// newTextModel creates a model to view text.
+func newTextModel(v views.Text, header string) textModel {
+	m := textModel{
+		Model: scroller.Model[views.Text]{
+			Header: header,
+			Data:   v,
+		},
+	}
+	lastTextID++
+	m.id = lastTextID
+	m.indexed, _ = v.Indexed()
+	m.length = v.Len(0)
+	m.ticking = m.busy()
+	return m
+}
+
+func (m textModel) Init() tea.Cmd {
+	if m.ticking {
+		return indexTick(m.id)
+	}
+	return nil
+}
+
+// busy reports whether the file is still being indexed or its lines
+// measured for wrapping.
+func (m textModel) busy() bool {
+	_, done := m.Data.Indexed()
+	return !done || m.Data.Wrapping()
+}
+
+func (m textModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(indexTickMsg); ok {
+		if msg.id != m.id {
+			return m, nil
+		}
+		m.ticking = false
+		indexed, _ := m.Data.Indexed()
+		length := m.Data.Len(m.Width())
+
+		switch {
+		case m.Data.Wrapped():
+			// Lines not yet measured count as one visual line, so keep
+			// the cursor on the same line as more are measured
+			m.SetCursor(m.Data.PosOf(m.line, m.Width()))
+		case m.Cursor() >= m.indexed:
+			// Lines past those indexed are counted from the end of the
+			// file, so keep the cursor on the same line as more are found
+			m.MoveCursor(length - m.length)
+		}
+		m.indexed, m.length = indexed, length
+		if m.busy() {
+			m.ticking = true
+			return m, indexTick(m.id)
+		}
+		return m, nil
+	}
+
+	mod, cmd := m.Model.Update(msg)
+	scr, ok := mod.(scroller.Model[views.Text])
+	if !ok {
+		return mod, cmd
+	}
+	m.Model = scr
+	if m.Data.Wrapped() {
+		m.line = m.Data.LineOf(m.Cursor(), m.Width())
+	}
+	if m.Data.Wrapping() && !m.ticking {
+		// Wrapping was just turned on, or the width changed
+		m.ticking = true
+		cmd = tea.Batch(cmd, indexTick(m.id))
+	}
+	return m, cmd
+}
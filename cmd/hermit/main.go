@@ -1,15 +1,27 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"image/color"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 
+	"github.com/ancientlore/hermit2/app"
 	"github.com/ancientlore/hermit2/browser"
 	"github.com/ancientlore/hermit2/config"
+	"github.com/ancientlore/hermit2/dialog"
+	"github.com/ancientlore/hermit2/jobs"
+	"github.com/ancientlore/hermit2/scroller"
+	"github.com/ancientlore/hermit2/theme"
+	"github.com/ancientlore/hermit2/views"
 	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/colorprofile"
 )
 
 func main() {
@@ -21,7 +33,8 @@ func main() {
 
 	// Process flags
 	var (
-		folder = flag.String("path", wd, "Startup path")
+		folder  = flag.String("path", wd, "Startup path")
+		cfgFile = flag.String("config", "", "Configuration file (default is "+config.ConfigFileName+" in the config folder)")
 	)
 	flag.Parse()
 
@@ -33,6 +46,40 @@ func main() {
 	}
 	fmt.Printf("Config folder: %s\n", cfgFolder)
 
+	// Load configuration
+	cfg, err := loadConfig(*cfgFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if cfg.File() != "" {
+		fmt.Printf("Config file:   %s\n", cfg.File())
+	}
+	config.Set(cfg)
+	applyConfig(cfg)
+	if err := errors.Join(applyFileColors(cfg), applyHighlight(cfg), browser.ApplyKeys(cfg)); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Bookmarks set in Hermit take the place of those in the configuration
+	browser.SetBookmarks(loadBookmarks(cfg))
+
+	// Use the configured startup path unless one was given
+	pathSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "path" {
+			pathSet = true
+		}
+	})
+	if !pathSet && cfg.General.StartupPath != "" {
+		*folder, err = config.ExpandPath(cfg.General.StartupPath)
+		if err != nil {
+			fmt.Printf("Invalid startup path: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
 	absFolder, err := filepath.Abs(*folder)
 	if err != nil {
 		fmt.Printf("Unable to get absolute path: %s\n", err)
@@ -41,24 +88,255 @@ func main() {
 
 	fmt.Printf("Shell:         %s\n", config.Shell())
 
-	// Get file system to open and path from folder
-	fsRoot := filepath.VolumeName(absFolder)
-	fsPath := strings.TrimPrefix(absFolder, fsRoot)
-	fsRoot += string(filepath.Separator)
+	// Run long operations in the background, reporting to the program
+	mgr := jobs.NewManager()
 
-	// fmt.Printf("Open folder %s on file system %s\n", fsPath, fsRoot)
+	// Reopen the tabs of the last session, unless a folder was given
+	var (
+		tabs   []tea.Model
+		active int
+	)
+	if cfg.General.Session && !pathSet {
+		tabs, active = restoreSession(mgr)
+	}
+	if len(tabs) == 0 {
+		// Create a browser, shown in one of two panes
+		m, err := browser.Open(absFolder)
+		if err != nil {
+			fmt.Printf("Error opening folder: %v\n", err)
+			os.Exit(1)
+		}
+		m.SetJobs(mgr)
+		panes, err := browser.NewPanes(*m, cfg.General.Panes == 2)
+		if err != nil {
+			fmt.Printf("Error opening folder: %v\n", err)
+			os.Exit(1)
+		}
+		tabs = []tea.Model{panes}
+	}
 
-	// Create a browser
-	m, err := browser.New(os.DirFS(fsRoot), fsRoot, filepath.ToSlash(fsPath))
+	// Open tea with and run the tabs
+	p := tea.NewProgram(app.NewTabs(tabs, active))
+	mgr.SetSender(p.Send)
+	final, err := p.Run()
 	if err != nil {
-		fmt.Printf("Error opening folder: %v\n", err)
+		fmt.Printf("Alas, there's been an error: %v\n", err)
 		os.Exit(1)
 	}
+	if cfg.General.Session {
+		if err := saveSession(final); err != nil {
+			fmt.Printf("Unable to save session: %v\n", err)
+		}
+	}
+}
 
-	// Open tea with and run the initial model
-	p := tea.NewProgram(*m)
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Alas, there's been an error: %v\n", err)
-		os.Exit(1)
+// loadBookmarks loads the bookmarks file, returning its location too. Until
+// the file is first saved, the bookmarks come from the configuration. Slots
+// that can't be read are reported and skipped.
+func loadBookmarks(cfg *config.Config) (config.Bookmarks, string) {
+	file, err := config.BookmarksFile()
+	if err != nil {
+		return cfg.DefaultBookmarks(), ""
+	}
+	b, err := config.LoadBookmarks(file)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return cfg.DefaultBookmarks(), file
+	case err != nil:
+		fmt.Printf("Ignoring bookmarks: %v\n", err)
+	}
+	return b, file
+}
+
+// restoreSession opens the tabs saved in the session file, returning them
+// and the index of the one to show. Tabs whose folders are gone are skipped.
+func restoreSession(mgr *jobs.Manager) ([]tea.Model, int) {
+	file, err := config.SessionFile()
+	if err != nil {
+		return nil, 0
+	}
+	s, err := config.LoadSession(file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("Ignoring session: %v\n", err)
+		}
+		return nil, 0
+	}
+	var (
+		tabs   []tea.Model
+		active int
+	)
+	for i, tab := range s.Tabs {
+		panes, err := browser.OpenTab(tab, mgr)
+		if err != nil {
+			fmt.Printf("Skipping tab: %v\n", err)
+			continue
+		}
+		if i <= s.Active {
+			active = len(tabs)
+		}
+		tabs = append(tabs, panes)
+	}
+	return tabs, active
+}
+
+// saveSession saves the tabs open when the program ended in the session
+// file.
+func saveSession(final tea.Model) error {
+	a, ok := final.(app.Model)
+	if !ok {
+		return nil
+	}
+	roots, active := a.Tabs()
+	s := config.Session{Active: active}
+	for _, root := range roots {
+		if panes, ok := root.(browser.Panes); ok {
+			s.Tabs = append(s.Tabs, panes.Tab())
+		}
+	}
+	file, err := config.SessionFile()
+	if err != nil {
+		return err
+	}
+	return s.Save(file)
+}
+
+// loadConfig loads the configuration file. A missing file is only an
+// error when it was named on the command line.
+func loadConfig(file string) (*config.Config, error) {
+	if file != "" {
+		return config.Load(file)
+	}
+	file, err := config.DefaultFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config.Default(), nil
+	}
+	return cfg, err
+}
+
+// applyConfig applies the configuration to the views.
+func applyConfig(cfg *config.Config) {
+	sortOrder, err := views.ParseSortOrder(string(cfg.General.Sort))
+	if err == nil {
+		views.DefaultSort = sortOrder
+	}
+	views.DefaultReverse = cfg.General.Reverse
+	views.ShowHidden = cfg.General.ShowHidden
+
+	t := loadTheme(cfg.Colors)
+	scroller.SetTheme(t)
+	views.SetTheme(t)
+	dialog.SetTheme(t)
+	browser.SetTheme(t)
+	app.SetTheme(t)
+}
+
+// loadTheme returns the configured theme with its colors overridden. When
+// NO_COLOR is set, the colorless theme is used instead.
+func loadTheme(c config.Colors) theme.Theme {
+	if theme.NoColor() {
+		return theme.Mono
+	}
+	t, ok := theme.Get(c.Theme)
+	if !ok {
+		t = theme.Default()
+	}
+	background(&t.Header, c.Header)
+	background(&t.Footer, c.Footer)
+	foreground(&t.Text, c.Text)
+	background(&t.Cursor, c.Cursor)
+	foreground(&t.Selected, c.Selected)
+	foreground(&t.Directory, c.Directory)
+	foreground(&t.Hidden, c.Hidden)
+	foreground(&t.HiddenDirectory, c.HiddenDirectory)
+	foreground(&t.Symlink, c.Symlink)
+	foreground(&t.Executable, c.Executable)
+	foreground(&t.Error, c.Error)
+	return t
+}
+
+// foreground sets the foreground of s to the configured color, if set.
+func foreground(s *lipgloss.Style, c config.Color) {
+	if col := toColor(c); col != nil {
+		*s = s.Foreground(col)
+	}
+}
+
+// background sets the background of s to the configured color, if set.
+func background(s *lipgloss.Style, c config.Color) {
+	if col := toColor(c); col != nil {
+		*s = s.Background(col)
+	}
+}
+
+// applyFileColors colors file names from the configured dircolors file, or
+// else from LS_COLORS. A bad LS_COLORS is reported but does not stop Hermit.
+func applyFileColors(cfg *config.Config) error {
+	if theme.NoColor() {
+		return nil
+	}
+	if cfg.Colors.Dircolors != "" {
+		file, err := config.ExpandPath(cfg.Colors.Dircolors)
+		if err == nil {
+			err = loadDircolors(file)
+		}
+		if err != nil {
+			return cfg.ErrorAt(err.Error(), "colors", "dircolors")
+		}
+		return nil
+	}
+	if cfg.Colors.LSColors {
+		if err := views.SetLSColors(os.Getenv("LS_COLORS")); err != nil {
+			fmt.Printf("Ignoring LS_COLORS: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// loadDircolors colors file names from a file in dircolors format.
+func loadDircolors(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := views.LoadDircolors(f); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// applyHighlight applies the highlight settings to the text viewer and
+// suits its colors to the terminal.
+func applyHighlight(cfg *config.Config) error {
+	views.SetColorProfile(colorprofile.Detect(os.Stdout, os.Environ()))
+
+	h := cfg.Highlight
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(h.Styles)) {
+		if err := views.DefineStyle(name, h.Styles[name]); err != nil {
+			errs = append(errs, cfg.ErrorAt(err.Error(), "highlight", "styles", name))
+		}
+	}
+	if err := views.SetStyle(h.Style); err != nil {
+		errs = append(errs, cfg.ErrorAt(err.Error(), "highlight", "style"))
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(h.Lexers)) {
+		if err := views.SetLexer(pattern, h.Lexers[pattern]); err != nil {
+			errs = append(errs, cfg.ErrorAt(err.Error(), "highlight", "lexers", pattern))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// toColor converts a configured color, returning nil when it is not set.
+func toColor(c config.Color) color.Color {
+	if c == "" {
+		return nil
 	}
+	return lipgloss.Color(string(c))
 }
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// BookmarksFileName is the name of the file in the config folder that keeps
+// the bookmarks set in Hermit.
+const BookmarksFileName = "bookmarks.toml"
+
+// Bookmark is a folder kept in one of the numbered slots.
+type Bookmark struct {
+	Name string `toml:"name"` // Name shown in the bookmark list
+	Path string `toml:"path"` // Folder, which may start with ~
+}
+
+// Bookmarks holds the bookmarks in slots 0 to 9. Empty slots have no path.
+type Bookmarks [10]Bookmark
+
+// Slot returns the bookmark slot named by a digit from 0 to 9.
+func Slot(s string) (int, bool) {
+	if len(s) != 1 || s[0] < '0' || s[0] > '9' {
+		return 0, false
+	}
+	return int(s[0] - '0'), true
+}
+
+// DefaultBookmarks returns the bookmarks in the [bookmarks] table of the
+// configuration, named after their folders. They are used until bookmarks
+// are first saved in the bookmarks file.
+func (c *Config) DefaultBookmarks() Bookmarks {
+	var b Bookmarks
+	for k, p := range c.Bookmarks {
+		if i, ok := Slot(k); ok {
+			b[i] = Bookmark{Name: filepath.Base(p), Path: p}
+		}
+	}
+	return b
+}
+
+// BookmarksFile returns the location of the bookmarks file in the config
+// folder.
+func BookmarksFile() (string, error) {
+	f, err := ConfigFolder()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(f, BookmarksFileName), nil
+}
+
+// LoadBookmarks reads the bookmarks saved in file. If the file does not
+// exist, the returned error wraps fs.ErrNotExist. Slots that are not valid
+// are left empty and reported in the error, along with the bookmarks that
+// are.
+func LoadBookmarks(file string) (Bookmarks, error) {
+	var (
+		b     Bookmarks
+		slots map[string]Bookmark
+		errs  []error
+	)
+	if _, err := toml.DecodeFile(file, &slots); err != nil {
+		return b, err
+	}
+	for _, k := range slices.Sorted(maps.Keys(slots)) {
+		i, ok := Slot(k)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: bookmark slot %q must be a digit from 0 to 9", file, k))
+		case slots[k].Path == "":
+			errs = append(errs, fmt.Errorf("%s: bookmark %q has an empty path", file, k))
+		default:
+			b[i] = slots[k]
+		}
+	}
+	return b, errors.Join(errs...)
+}
+
+// Save writes the bookmarks to file, leaving out empty slots.
+func (b *Bookmarks) Save(file string) error {
+	slots := make(map[string]Bookmark)
+	for i, bm := range b {
+		if bm.Path != "" {
+			slots[strconv.Itoa(i)] = bm
+		}
+	}
+	return writeTOML(file, slots)
+}
@@ -0,0 +1,372 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ancientlore/hermit2/theme"
+)
+
+// Config holds the settings read from the hermit configuration file.
+type Config struct {
+	General   General             `toml:"general"`
+	Colors    Colors              `toml:"colors"`
+	Highlight Highlight           `toml:"highlight"`
+	Shell     ShellConfig         `toml:"shell"`
+	Bookmarks map[string]string   `toml:"bookmarks"`
+	Commands  map[string]Command  `toml:"commands"`
+	Keys      map[string][]string `toml:"keys"`
+
+	file string // The file the configuration was read from, if any
+	src  string // The contents of the file, for locating errors
+}
+
+// General holds general settings.
+type General struct {
+	StartupPath string    `toml:"startup_path"` // Folder to open when -path is not given
+	Sort        SortOrder `toml:"sort"`         // Default sort order for folders
+	Reverse     bool      `toml:"reverse"`      // Whether the default sort is reversed
+	ShowHidden  bool      `toml:"show_hidden"`  // Whether dot files are listed
+	Panes       int       `toml:"panes"`        // Number of browser panes shown at startup, 1 or 2
+	Session     bool      `toml:"session"`      // Whether the open tabs are saved on exit and reopened
+}
+
+// Colors names the theme and holds overrides of its colors. Empty values
+// keep the colors of the theme.
+type Colors struct {
+	Theme           string `toml:"theme"`            // Name of a built-in theme
+	Header          Color  `toml:"header"`           // Header background
+	Footer          Color  `toml:"footer"`           // Footer background
+	Text            Color  `toml:"text"`             // Normal text
+	Cursor          Color  `toml:"cursor"`           // Cursor line background
+	Selected        Color  `toml:"selected"`         // Selected names
+	Directory       Color  `toml:"directory"`        // Folder names
+	Hidden          Color  `toml:"hidden"`           // Dot file names
+	HiddenDirectory Color  `toml:"hidden_directory"` // Dot folder names
+	Symlink         Color  `toml:"symlink"`          // Symbolic link names
+	Executable      Color  `toml:"executable"`       // Executable file names
+	Error           Color  `toml:"error"`            // Errors
+	LSColors        bool   `toml:"ls_colors"`        // Whether file names are colored as LS_COLORS says
+	Dircolors       string `toml:"dircolors"`        // File in dircolors format to color file names with
+}
+
+// Highlight holds settings for syntax highlighting in the text viewer.
+type Highlight struct {
+	Style  string                       `toml:"style"`  // Chroma style, built in or defined in Styles
+	Styles map[string]map[string]string `toml:"styles"` // Custom styles, mapping token types to style entries
+	Lexers map[string]string            `toml:"lexers"` // Lexer names by file name pattern
+}
+
+// ShellConfig holds settings for running the shell.
+type ShellConfig struct {
+	Program string `toml:"program"` // Shell to run; overridden by $HERMIT_SHELL
+}
+
+// Command is a user-defined command bound to a single key.
+type Command struct {
+	Macro       string `toml:"macro"`       // Command line to run, with macro substitutions
+	Description string `toml:"description"` // Text shown in menus
+}
+
+// SortOrder names a way to sort folder listings.
+type SortOrder string
+
+// Sort orders understood in the configuration file.
+const (
+	SortByName SortOrder = "name"
+	SortByExt  SortOrder = "ext"
+	SortBySize SortOrder = "size"
+	SortByDate SortOrder = "date"
+)
+
+// UnmarshalText validates the sort order.
+func (s *SortOrder) UnmarshalText(b []byte) error {
+	switch o := SortOrder(strings.ToLower(string(b))); o {
+	case SortByName, SortByExt, SortBySize, SortByDate:
+		*s = o
+		return nil
+	}
+	return fmt.Errorf("invalid sort order %q (use name, ext, size or date)", string(b))
+}
+
+// Color is a color given either as "#RGB", "#RRGGBB" or an ANSI color number.
+type Color string
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// UnmarshalText validates the color.
+func (c *Color) UnmarshalText(b []byte) error {
+	s := string(b)
+	if hexColor.MatchString(s) {
+		*c = Color(s)
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		*c = Color(s)
+		return nil
+	}
+	return fmt.Errorf("invalid color %q (use #RGB, #RRGGBB or 0-255)", s)
+}
+
+// Error describes a problem at a position in the configuration file.
+type Error struct {
+	File   string // Name of the configuration file
+	Line   int    // Line number, starting at 1; 0 if unknown
+	Column int    // Column number, starting at 1; 0 if unknown
+	Msg    string // Description of the problem
+}
+
+func (e Error) Error() string {
+	f := e.File
+	if f == "" {
+		f = ConfigFileName
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", f, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", f, e.Line, e.Column, e.Msg)
+}
+
+var current = Default()
+
+// Current returns the active configuration.
+func Current() *Config {
+	return current
+}
+
+// Set makes c the active configuration.
+func Set(c *Config) {
+	current = c
+}
+
+// Default returns the configuration used when no file is present.
+func Default() *Config {
+	return &Config{
+		General: General{
+			Sort:       SortByExt,
+			ShowHidden: true,
+			Panes:      1,
+		},
+		Colors: Colors{
+			Theme:    theme.DefaultName,
+			LSColors: true,
+		},
+		Highlight: Highlight{
+			Style:  "hermit",
+			Styles: map[string]map[string]string{},
+			Lexers: map[string]string{},
+		},
+		Bookmarks: map[string]string{},
+		Commands:  map[string]Command{},
+		Keys:      map[string][]string{},
+	}
+}
+
+// File returns the name of the file the configuration was loaded from.
+func (c *Config) File() string {
+	return c.file
+}
+
+// ErrorAt returns an Error describing a problem with the setting named
+// by key, positioned where the setting appears in the configuration file.
+func (c *Config) ErrorAt(msg string, key ...string) error {
+	line, col := locate(c.src, key)
+	return Error{File: c.file, Line: line, Column: col, Msg: msg}
+}
+
+// DefaultFile returns the location of the configuration file in the
+// config folder.
+func DefaultFile() (string, error) {
+	f, err := ConfigFolder()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(f, ConfigFileName), nil
+}
+
+// Load reads and validates the configuration in file. Settings not present
+// in the file keep their default values. If the file does not exist, the
+// returned error wraps fs.ErrNotExist.
+func Load(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := Default()
+	c.file = file
+	c.src = string(data)
+	md, err := toml.Decode(string(data), c)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			return nil, Error{File: file, Line: pe.Position.Line, Column: pe.Position.Col, Msg: pe.Message}
+		}
+		return nil, Error{File: file, Msg: err.Error()}
+	}
+
+	var errs []error
+	for _, k := range md.Undecoded() {
+		errs = append(errs, c.ErrorAt(fmt.Sprintf("unknown setting %q", k.String()), k...))
+	}
+	for _, e := range c.validate() {
+		errs = append(errs, c.ErrorAt(e.msg, e.key...))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return c, nil
+}
+
+// keyError is a validation error for a specific key, to be located in the source.
+type keyError struct {
+	key toml.Key
+	msg string
+}
+
+// validate checks settings that cannot be checked while decoding.
+func (c *Config) validate() []keyError {
+	var errs []keyError
+	if c.General.Panes != 1 && c.General.Panes != 2 {
+		errs = append(errs, keyError{toml.Key{"general", "panes"}, fmt.Sprintf("panes must be 1 or 2, not %d", c.General.Panes)})
+	}
+	if _, ok := theme.Get(c.Colors.Theme); !ok {
+		errs = append(errs, keyError{toml.Key{"colors", "theme"}, fmt.Sprintf("unknown theme %q (use %s)", c.Colors.Theme, strings.Join(theme.Names(), ", "))})
+	}
+	for _, k := range slices.Sorted(maps.Keys(c.Bookmarks)) {
+		if _, ok := Slot(k); !ok {
+			errs = append(errs, keyError{toml.Key{"bookmarks", k}, fmt.Sprintf("bookmark slot %q must be a digit from 0 to 9", k)})
+		} else if c.Bookmarks[k] == "" {
+			errs = append(errs, keyError{toml.Key{"bookmarks", k}, fmt.Sprintf("bookmark %q has an empty path", k)})
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(c.Commands)) {
+		if len([]rune(k)) != 1 {
+			errs = append(errs, keyError{toml.Key{"commands", k}, fmt.Sprintf("command key %q must be a single character", k)})
+		}
+		if strings.TrimSpace(c.Commands[k].Macro) == "" {
+			errs = append(errs, keyError{toml.Key{"commands", k}, fmt.Sprintf("command %q has no macro", k)})
+		}
+	}
+
+	return errs
+}
+
+// locate finds the line and column where key is defined in the TOML source.
+// It understands table headers and simple "key = value" lines, with bare or
+// quoted keys and trailing comments, which covers the layout of the hermit
+// configuration file. It returns zeros if the key cannot be found.
+func locate(src string, key toml.Key) (int, int) {
+	var table toml.Key
+	bestLine, bestCol, best := 0, 0, 0
+	for i, line := range strings.Split(src, "\n") {
+		t := strings.TrimSpace(line)
+		col := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+		if strings.HasPrefix(t, "[") {
+			// A table header, or a header of an array of tables
+			open, close := "[", "]"
+			if strings.HasPrefix(t, "[[") {
+				open, close = "[[", "]]"
+			}
+			k, rest, ok := scanKey(t[len(open):])
+			if !ok || !strings.HasPrefix(rest, close) {
+				continue
+			}
+			table = k
+			if n := matchKey(table, key); n > best {
+				bestLine, bestCol, best = i+1, col, n
+			}
+			continue
+		}
+		k, rest, ok := scanKey(t)
+		if !ok || !strings.HasPrefix(rest, "=") || matchKey(table, key) < len(table) {
+			continue
+		}
+		full := append(append(toml.Key{}, table...), k...)
+		if n := matchKey(full, key); n > best {
+			bestLine, bestCol, best = i+1, col, n
+		}
+	}
+	return bestLine, bestCol
+}
+
+// scanKey reads a dotted TOML key, whose parts may be bare or quoted, from
+// the start of s. It returns the parts without quotes and the rest of s
+// after any spaces. It reports false if s does not start with a key.
+func scanKey(s string) (toml.Key, string, bool) {
+	var k toml.Key
+	for {
+		s = strings.TrimLeft(s, " \t")
+		var part string
+		switch {
+		case strings.HasPrefix(s, `"`):
+			i := 1
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(s) {
+				return nil, "", false
+			}
+			p, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				p = s[1:i]
+			}
+			part, s = p, s[i+1:]
+		case strings.HasPrefix(s, "'"):
+			i := strings.IndexByte(s[1:], '\'')
+			if i < 0 {
+				return nil, "", false
+			}
+			part, s = s[1:i+1], s[i+2:]
+		default:
+			i := strings.IndexFunc(s, func(r rune) bool {
+				return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+			})
+			if i < 0 {
+				i = len(s)
+			}
+			if i == 0 {
+				return nil, "", false
+			}
+			part, s = s[:i], s[i:]
+		}
+		k = append(k, part)
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, ".") {
+			return k, s, true
+		}
+		s = s[1:]
+	}
+}
+
+// matchKey returns the length of k if it is a prefix of key, or 0 otherwise.
+func matchKey(k, key toml.Key) int {
+	if len(k) > len(key) {
+		return 0
+	}
+	for i := range k {
+		if k[i] != key[i] {
+			return 0
+		}
+	}
+	return len(k)
+}
+
+// ExpandPath expands a leading "~" to the home folder and returns
+// the absolute path.
+func ExpandPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		p = filepath.Join(HomeFolder(), p[1:])
+	}
+	return filepath.Abs(p)
+}
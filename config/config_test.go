@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		key       toml.Key
+		line, col int
+	}{
+		{"plain", "[general]\npanes = 3", toml.Key{"general", "panes"}, 2, 1},
+		{"header comment", "[general]   # settings\npanes = 3", toml.Key{"general", "panes"}, 2, 1},
+		{"value comment", "[general]\nsort = \"ext\" # order\npanes = 3 # two", toml.Key{"general", "panes"}, 3, 1},
+		{"indented", "[general]\n  panes = 3", toml.Key{"general", "panes"}, 2, 3},
+		{"table only", "[colors] # theme\ntheme = \"x\"", toml.Key{"colors"}, 1, 1},
+		{"other table", "[general]\npanes = 1\n[colors]\npanes = 3", toml.Key{"general", "panes"}, 2, 1},
+		{"quoted key", "[bookmarks] # slots\n\"a.b\" = \"~\"", toml.Key{"bookmarks", "a.b"}, 2, 1},
+		{"literal key", "[bookmarks]\n'x=y' = \"~\"", toml.Key{"bookmarks", "x=y"}, 2, 1},
+		{"quoted header", "[highlight.styles.\"my.style\"] # custom\nKeyword = \"bold\"", toml.Key{"highlight", "styles", "my.style", "Keyword"}, 2, 1},
+		{"dotted key", "[highlight]\nstyles.mine.Keyword = \"bold\"", toml.Key{"highlight", "styles", "mine", "Keyword"}, 2, 1},
+		{"comment line", "[general]\n# panes = 2\npanes = 3", toml.Key{"general", "panes"}, 3, 1},
+		{"missing", "[general]\npanes = 3", toml.Key{"colors", "theme"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col := locate(tt.src, tt.key)
+			if line != tt.line || col != tt.col {
+				t.Errorf("locate(%q, %v) = %d:%d, want %d:%d", tt.src, tt.key, line, col, tt.line, tt.col)
+			}
+		})
+	}
+}
+
+func TestLoadErrorPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"header comment", "[general] # x\npanes = 3", ":2:1: panes must be 1 or 2"},
+		{"quoted slot", "[bookmarks] # slots\n\"ab\" = \"~/x\"", ":2:1: bookmark slot \"ab\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), ConfigFileName)
+			if err := os.WriteFile(file, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(file)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBookmarksSkipsBadSlots(t *testing.T) {
+	file := filepath.Join(t.TempDir(), BookmarksFileName)
+	src := `[1]
+name = "src"
+path = "~/src"
+
+[x]
+path = "~/x"
+
+[2]
+name = "empty"
+
+[3]
+path = "/tmp"
+`
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBookmarks(file)
+	if err == nil {
+		t.Fatal("no error for the bad slots")
+	}
+	for _, want := range []string{`slot "x"`, `bookmark "2" has an empty path`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	want := Bookmarks{1: {Name: "src", Path: "~/src"}, 3: {Path: "/tmp"}}
+	if b != want {
+		t.Errorf("bookmarks = %v, want %v", b, want)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SessionFileName is the name of the file in the config folder that keeps
+// the open tabs from one run to the next.
+const SessionFileName = "session.toml"
+
+// Session holds the tabs that were open when Hermit last exited.
+type Session struct {
+	Active int   `toml:"active"` // Index of the tab shown
+	Tabs   []Tab `toml:"tabs"`
+}
+
+// Tab holds the folders shown in a tab.
+type Tab struct {
+	Folders []string `toml:"folders"` // Folders of the panes, on disk
+	Active  int      `toml:"active"`  // Index of the pane with the focus
+	Split   bool     `toml:"split"`   // Whether both panes are shown
+}
+
+// SessionFile returns the location of the session file in the config
+// folder.
+func SessionFile() (string, error) {
+	f, err := ConfigFolder()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(f, SessionFileName), nil
+}
+
+// LoadSession reads the session saved in file. If the file does not exist,
+// the returned error wraps fs.ErrNotExist.
+func LoadSession(file string) (*Session, error) {
+	var s Session
+	if _, err := toml.DecodeFile(file, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save writes the session to file.
+func (s *Session) Save(file string) error {
+	return writeTOML(file, s)
+}
+
+// writeTOML writes v to file in TOML, replacing the file only once it is
+// complete.
+func writeTOML(file string, v any) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
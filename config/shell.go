@@ -6,8 +6,13 @@ import (
 	"os"
 )
 
+// Shell returns the shell to run, from $HERMIT_SHELL, the configuration
+// file or $SHELL, in that order.
 func Shell() string {
 	shell := os.Getenv("HERMIT_SHELL")
+	if shell == "" {
+		shell = Current().Shell.Program
+	}
 	if shell == "" {
 		shell = os.Getenv("SHELL")
 	}
@@ -16,3 +21,8 @@ func Shell() string {
 	}
 	return shell
 }
+
+// ShellFlag returns the flag that makes the shell run a command line.
+func ShellFlag() string {
+	return "-c"
+}
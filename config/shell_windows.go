@@ -2,10 +2,19 @@
 
 package config
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
 
+// Shell returns the shell to run, from $HERMIT_SHELL, the configuration
+// file or $SHELL, in that order.
 func Shell() string {
 	shell := os.Getenv("HERMIT_SHELL")
+	if shell == "" {
+		shell = Current().Shell.Program
+	}
 	if shell == "" {
 		shell = os.Getenv("SHELL")
 	}
@@ -17,3 +26,15 @@ func Shell() string {
 	}
 	return shell
 }
+
+// ShellFlag returns the flag that makes the shell run a command line.
+func ShellFlag() string {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(Shell()), filepath.Ext(Shell())))
+	switch name {
+	case "cmd":
+		return "/c"
+	case "powershell", "pwsh":
+		return "-Command"
+	}
+	return "-c"
+}
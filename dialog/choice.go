@@ -0,0 +1,87 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maxRows is the number of choices shown at once.
+const maxRows = 10
+
+// Choice is a dialog that picks one of a list of choices. It sends a
+// ChoiceMsg when enter is pressed and a CancelMsg when escape is pressed.
+type Choice struct {
+	id      any
+	title   string
+	choices []string
+	cursor  int
+	offset  int
+}
+
+// NewChoice creates a choice dialog with the cursor on the first choice.
+func NewChoice(id any, title string, choices []string) Choice {
+	return Choice{id: id, title: title, choices: choices}
+}
+
+// Cursor returns the position of the cursor.
+func (d Choice) Cursor() int {
+	return d.cursor
+}
+
+func (d Choice) Update(msg tea.Msg) (Dialog, tea.Cmd) {
+	km, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return d, nil
+	}
+	switch km.String() {
+	case "esc", "ctrl+c":
+		return nil, send(CancelMsg{ID: d.id})
+	case "enter":
+		if d.cursor < len(d.choices) {
+			return nil, send(ChoiceMsg{ID: d.id, Index: d.cursor, Choice: d.choices[d.cursor]})
+		}
+		return nil, send(CancelMsg{ID: d.id})
+	case "up":
+		d.cursor--
+	case "down":
+		d.cursor++
+	case "pgup", "shift+up":
+		d.cursor -= maxRows
+	case "pgdown", "shift+down":
+		d.cursor += maxRows
+	case "home", "ctrl+up":
+		d.cursor = 0
+	case "end", "ctrl+down":
+		d.cursor = len(d.choices) - 1
+	}
+	d.cursor = max(min(d.cursor, len(d.choices)-1), 0)
+	if d.cursor < d.offset {
+		d.offset = d.cursor
+	} else if d.cursor >= d.offset+maxRows {
+		d.offset = d.cursor - maxRows + 1
+	}
+	return d, nil
+}
+
+func (d Choice) View(width int) string {
+	w := innerWidth(width)
+	lines := []string{title.Width(w).Render(d.title)}
+	for i := d.offset; i < len(d.choices) && i < d.offset+maxRows; i++ {
+		// Truncated and padded by the width shown, not by bytes
+		s := ansi.Truncate(d.choices[i], w, "")
+		s += strings.Repeat(" ", max(w-ansi.StringWidth(s), 0))
+		if i == d.cursor {
+			s = selected.Render(s)
+		}
+		lines = append(lines, s)
+	}
+	if len(d.choices) == 0 {
+		lines = append(lines, hint.Width(w).Render("Nothing to choose, esc to cancel"))
+	} else {
+		lines = append(lines, hint.Width(w).Render(fmt.Sprintf("%d of %d, enter to pick, esc to cancel", d.cursor+1, len(d.choices))))
+	}
+	return render(lines...)
+}
@@ -0,0 +1,36 @@
+package dialog
+
+import (
+	tea "charm.land/bubbletea/v2"
+)
+
+// Confirm is a dialog that asks a yes/no question. It sends a ConfirmMsg
+// with the answer; escape answers no.
+type Confirm struct {
+	id       any
+	question string
+}
+
+// NewConfirm creates a confirm dialog.
+func NewConfirm(id any, question string) Confirm {
+	return Confirm{id: id, question: question}
+}
+
+func (d Confirm) Update(msg tea.Msg) (Dialog, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch msg.String() {
+		case "y", "Y":
+			return nil, send(ConfirmMsg{ID: d.id, Yes: true})
+		case "n", "N", "esc", "ctrl+c":
+			return nil, send(ConfirmMsg{ID: d.id, Yes: false})
+		}
+	}
+	return d, nil
+}
+
+func (d Confirm) View(width int) string {
+	return render(
+		title.Width(innerWidth(width)).Render(d.question),
+		hint.Render("y for yes, n for no"),
+	)
+}
@@ -0,0 +1,132 @@
+// Package dialog provides modal dialogs that are drawn centered over a view.
+// A dialog takes the keys until it is dismissed, and then delivers its result
+// as a message. Each dialog is created with an ID, which may be any value and
+// is returned in the result so the caller can tell which request it answers.
+package dialog
+
+import (
+	"github.com/ancientlore/hermit2/theme"
+	"charm.land/lipgloss/v2"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Dialog is a modal dialog.
+type Dialog interface {
+	// Update handles a message. It returns nil once the dialog is dismissed.
+	Update(msg tea.Msg) (Dialog, tea.Cmd)
+
+	// View renders the dialog box no wider than width.
+	View(width int) string
+}
+
+// OpenMsg asks the model hosting dialogs to open one.
+type OpenMsg struct {
+	Dialog Dialog
+}
+
+// Open returns a command that opens the dialog.
+func Open(d Dialog) tea.Cmd {
+	return func() tea.Msg {
+		return OpenMsg{Dialog: d}
+	}
+}
+
+// ShowError returns a command that opens a dialog showing the error.
+func ShowError(err error) tea.Cmd {
+	return Open(NewError(err))
+}
+
+// InputMsg is sent when an input dialog is accepted.
+type InputMsg struct {
+	ID     any
+	Value  string
+	Option int // The option chosen, if the dialog offers options
+}
+
+// ChangeMsg is sent when the value of an input dialog is edited.
+type ChangeMsg struct {
+	ID    any
+	Value string
+}
+
+// ConfirmMsg is sent when a confirm dialog is answered.
+type ConfirmMsg struct {
+	ID  any
+	Yes bool
+}
+
+// ChoiceMsg is sent when an entry of a choice dialog is picked.
+type ChoiceMsg struct {
+	ID     any
+	Index  int    // Position of the choice
+	Choice string // Text of the choice
+}
+
+// CancelMsg is sent when an input or choice dialog is canceled.
+type CancelMsg struct {
+	ID any
+}
+
+// CloseMsg is sent when a message dialog is dismissed.
+type CloseMsg struct {
+	ID any
+}
+
+// send returns a command that delivers msg.
+func send(msg tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return msg
+	}
+}
+
+var (
+	box        = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	title      = lipgloss.NewStyle().Bold(true)
+	errorTitle = theme.Default().Error.Bold(true)
+	hint       = lipgloss.NewStyle().Faint(true)
+	selected   = lipgloss.NewStyle().Reverse(true)
+)
+
+// SetTheme sets the style of the title of error dialogs.
+func SetTheme(t theme.Theme) {
+	errorTitle = t.Error.Bold(true)
+}
+
+// MaxWidth is the widest a dialog box is drawn.
+const MaxWidth = 72
+
+// render draws the dialog box around the lines of content.
+func render(lines ...string) string {
+	return box.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// innerWidth returns the width available for content in a box no
+// wider than width.
+func innerWidth(width int) int {
+	w := min(width, MaxWidth) - box.GetHorizontalFrameSize()
+	return max(w, 10)
+}
+
+// Overlay draws the dialog centered over the base view.
+func Overlay(base string, d Dialog) string {
+	width, height := lipgloss.Width(base), lipgloss.Height(base)
+	dlg := d.View(width)
+	x := max((width-lipgloss.Width(dlg))/2, 0)
+	y := max((height-lipgloss.Height(dlg))/2, 0)
+	c := lipgloss.NewCanvas(width, height)
+	c.Compose(lipgloss.NewCompositor(
+		lipgloss.NewLayer(base),
+		lipgloss.NewLayer(dlg).X(x).Y(y).Z(1),
+	))
+	return c.Render()
+}
+
+// Captures reports whether msg is user input, which an open dialog
+// takes from the view beneath it.
+func Captures(msg tea.Msg) bool {
+	switch msg.(type) {
+	case tea.KeyPressMsg, tea.KeyReleaseMsg, tea.MouseMsg, tea.PasteMsg, tea.PasteStartMsg, tea.PasteEndMsg:
+		return true
+	}
+	return false
+}
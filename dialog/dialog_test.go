@@ -0,0 +1,282 @@
+package dialog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// press returns the message for a key, named as in tea.KeyPressMsg.String.
+func press(k string) tea.KeyPressMsg {
+	switch k {
+	case "enter":
+		return tea.KeyPressMsg{Code: tea.KeyEnter}
+	case "esc":
+		return tea.KeyPressMsg{Code: tea.KeyEscape}
+	case "space":
+		return tea.KeyPressMsg{Code: tea.KeySpace, Text: " "}
+	case "tab":
+		return tea.KeyPressMsg{Code: tea.KeyTab}
+	case "up":
+		return tea.KeyPressMsg{Code: tea.KeyUp}
+	case "down":
+		return tea.KeyPressMsg{Code: tea.KeyDown}
+	case "home":
+		return tea.KeyPressMsg{Code: tea.KeyHome}
+	case "end":
+		return tea.KeyPressMsg{Code: tea.KeyEnd}
+	case "pgdown":
+		return tea.KeyPressMsg{Code: tea.KeyPgDown}
+	case "pgup":
+		return tea.KeyPressMsg{Code: tea.KeyPgUp}
+	}
+	r, _ := utf8.DecodeRuneInString(k)
+	return tea.KeyPressMsg{Code: r, Text: k}
+}
+
+// messages runs a command and returns the messages it sends, leaving out
+// any that take a while, such as cursor blinks.
+func messages(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	ch := make(chan tea.Msg, 1)
+	go func() { ch <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-ch:
+	case <-time.After(50 * time.Millisecond):
+		return nil
+	}
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, messages(c)...)
+		}
+		return msgs
+	}
+	if msg == nil {
+		return nil
+	}
+	return []tea.Msg{msg}
+}
+
+// only returns the single message a command sends.
+func only(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	msgs := messages(cmd)
+	if len(msgs) != 1 {
+		t.Fatalf("got messages %#v, want one", msgs)
+	}
+	return msgs[0]
+}
+
+// checkWidth checks that every line of a dialog fits the width and is
+// valid UTF-8.
+func checkWidth(t *testing.T, view string, width int) {
+	t.Helper()
+	if !utf8.ValidString(view) {
+		t.Errorf("view is not valid UTF-8: %q", view)
+	}
+	for _, line := range strings.Split(view, "\n") {
+		if w := ansi.StringWidth(line); w > width {
+			t.Errorf("line %q is %d wide, more than %d", line, w, width)
+		}
+	}
+}
+
+func TestInput(t *testing.T) {
+	var d Dialog = NewInput("id", "Name:", "ab")
+
+	d, cmd := d.Update(press("c"))
+	if d == nil {
+		t.Fatal("input closed on a letter")
+	}
+	var change *ChangeMsg
+	for _, msg := range messages(cmd) {
+		if m, ok := msg.(ChangeMsg); ok {
+			change = &m
+		}
+	}
+	if change == nil || change.ID != "id" || change.Value != "abc" {
+		t.Errorf("change = %+v, want abc for id", change)
+	}
+
+	// Keys that don't change the value send no ChangeMsg
+	d, cmd = d.Update(press("left"))
+	for _, msg := range messages(cmd) {
+		if _, ok := msg.(ChangeMsg); ok {
+			t.Errorf("got %+v for a key that changes nothing", msg)
+		}
+	}
+
+	d, cmd = d.Update(press("enter"))
+	if d != nil {
+		t.Error("input still open after enter")
+	}
+	if msg, ok := only(t, cmd).(InputMsg); !ok || msg.ID != "id" || msg.Value != "abc" {
+		t.Errorf("enter sent %+v, want InputMsg abc", msg)
+	}
+}
+
+func TestInputCancel(t *testing.T) {
+	d, cmd := NewInput(1, "Name:", "x").Update(press("esc"))
+	if d != nil {
+		t.Error("input still open after esc")
+	}
+	if msg, ok := only(t, cmd).(CancelMsg); !ok || msg.ID != 1 {
+		t.Errorf("esc sent %+v, want CancelMsg", msg)
+	}
+}
+
+func TestInputOptions(t *testing.T) {
+	var d Dialog = NewInput(nil, "Name:", "").WithOptions([]string{"a", "b", "c"}, 1)
+	d, _ = d.Update(press("tab"))
+	d, _ = d.Update(press("tab"))
+	if !strings.Contains(d.View(80), "[a]") {
+		t.Errorf("view after two tabs from b doesn't show [a]:\n%s", d.View(80))
+	}
+	_, cmd := d.Update(press("enter"))
+	if msg := only(t, cmd).(InputMsg); msg.Option != 0 {
+		t.Errorf("option = %d, want 0", msg.Option)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		key  string
+		yes  bool
+		done bool
+	}{
+		{"y", true, true},
+		{"Y", true, true},
+		{"n", false, true},
+		{"N", false, true},
+		{"esc", false, true},
+		{"x", false, false},
+		{"enter", false, false},
+	}
+	for _, tt := range tests {
+		d, cmd := NewConfirm("q", "Sure?").Update(press(tt.key))
+		if !tt.done {
+			if d == nil || cmd != nil {
+				t.Errorf("%s closed the dialog", tt.key)
+			}
+			continue
+		}
+		if d != nil {
+			t.Errorf("%s left the dialog open", tt.key)
+		}
+		if msg, ok := only(t, cmd).(ConfirmMsg); !ok || msg.ID != "q" || msg.Yes != tt.yes {
+			t.Errorf("%s sent %+v, want Yes=%v", tt.key, msg, tt.yes)
+		}
+	}
+}
+
+func TestChoiceCursor(t *testing.T) {
+	choices := make([]string, 25)
+	for i := range choices {
+		choices[i] = string(rune('a' + i))
+	}
+	tests := []struct {
+		keys []string
+		want int
+	}{
+		{[]string{"up"}, 0},
+		{[]string{"down", "down"}, 2},
+		{[]string{"end"}, 24},
+		{[]string{"end", "down"}, 24},
+		{[]string{"end", "home"}, 0},
+		{[]string{"pgdown", "pgdown", "pgdown"}, 24},
+		{[]string{"end", "pgup", "pgup", "pgup"}, 0},
+	}
+	for _, tt := range tests {
+		var d Dialog = NewChoice(nil, "Pick", choices)
+		for _, k := range tt.keys {
+			d, _ = d.Update(press(k))
+		}
+		if got := d.(Choice).Cursor(); got != tt.want {
+			t.Errorf("%v: cursor = %d, want %d", tt.keys, got, tt.want)
+		}
+		// The cursor is always on a row that is shown
+		if view := d.View(80); !strings.Contains(view, choices[tt.want]) {
+			t.Errorf("%v: choice %q not shown", tt.keys, choices[tt.want])
+		}
+	}
+}
+
+func TestChoicePick(t *testing.T) {
+	var d Dialog = NewChoice("c", "Pick", []string{"one", "two"})
+	d, _ = d.Update(press("down"))
+	d, cmd := d.Update(press("enter"))
+	if d != nil {
+		t.Error("choice still open after enter")
+	}
+	if msg, ok := only(t, cmd).(ChoiceMsg); !ok || msg.ID != "c" || msg.Index != 1 || msg.Choice != "two" {
+		t.Errorf("enter sent %+v, want two", msg)
+	}
+
+	_, cmd = NewChoice("c", "Pick", []string{"one"}).Update(press("esc"))
+	if _, ok := only(t, cmd).(CancelMsg); !ok {
+		t.Error("esc did not cancel")
+	}
+}
+
+func TestChoiceEmpty(t *testing.T) {
+	var d Dialog = NewChoice("e", "Pick", nil)
+	for _, k := range []string{"down", "end", "up", "pgdown"} {
+		d, _ = d.Update(press(k))
+	}
+	if got := d.(Choice).Cursor(); got != 0 {
+		t.Errorf("cursor = %d, want 0", got)
+	}
+	if view := d.View(80); strings.Contains(view, "of 0") {
+		t.Errorf("empty choice shows a position:\n%s", view)
+	}
+	d, cmd := d.Update(press("enter"))
+	if d != nil {
+		t.Error("empty choice still open after enter")
+	}
+	if _, ok := only(t, cmd).(CancelMsg); !ok {
+		t.Error("enter on an empty choice did not cancel")
+	}
+}
+
+func TestChoiceTruncate(t *testing.T) {
+	long := strings.Repeat("é", 100)
+	styled := "\x1b[1m" + strings.Repeat("日本", 50) + "\x1b[0m"
+	d := NewChoice(nil, "Pick", []string{long, styled, "short"})
+	for _, width := range []int{20, 40, 80} {
+		checkWidth(t, d.View(width), min(width, MaxWidth))
+	}
+}
+
+func TestMessage(t *testing.T) {
+	for _, k := range []string{"enter", "esc", "space"} {
+		d, cmd := NewMessage("m", "Title", "text").Update(press(k))
+		if d != nil {
+			t.Errorf("%s left the message open", k)
+		}
+		if msg, ok := only(t, cmd).(CloseMsg); !ok || msg.ID != "m" {
+			t.Errorf("%s sent %+v, want CloseMsg", k, msg)
+		}
+	}
+	d, cmd := NewMessage("m", "Title", "text").Update(press("x"))
+	if d == nil || cmd != nil {
+		t.Error("x closed the message")
+	}
+}
+
+func TestError(t *testing.T) {
+	d := NewError(errors.New("it broke"))
+	view := ansi.Strip(d.View(80))
+	if !strings.Contains(view, "Error") || !strings.Contains(view, "it broke") {
+		t.Errorf("error view:\n%s", view)
+	}
+	checkWidth(t, d.View(30), 30)
+}
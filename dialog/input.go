@@ -0,0 +1,80 @@
+package dialog
+
+import (
+	"fmt"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Input is a dialog that reads a line of text. It sends a ChangeMsg as the
+// value is edited, an InputMsg when enter is pressed and a CancelMsg when
+// escape is pressed. It may also offer options, which tab cycles through.
+type Input struct {
+	id      any
+	title   string
+	input   textinput.Model
+	options []string // Options to choose from, if any
+	option  int      // The chosen option
+}
+
+// NewInput creates an input dialog with an initial value.
+func NewInput(id any, title, value string) Input {
+	in := textinput.New()
+	in.Prompt = "> "
+	in.SetValue(value)
+	in.CursorEnd()
+	in.Focus()
+	return Input{id: id, title: title, input: in}
+}
+
+// WithOptions returns the dialog offering a choice of options, starting
+// with the selected one.
+func (d Input) WithOptions(options []string, selected int) Input {
+	d.options = options
+	d.option = selected
+	return d
+}
+
+// Value returns the text entered so far.
+func (d Input) Value() string {
+	return d.input.Value()
+}
+
+func (d Input) Update(msg tea.Msg) (Dialog, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			return nil, send(CancelMsg{ID: d.id})
+		case "enter":
+			return nil, send(InputMsg{ID: d.id, Value: d.input.Value(), Option: d.option})
+		case "tab":
+			if len(d.options) > 0 {
+				d.option = (d.option + 1) % len(d.options)
+				return d, nil
+			}
+		}
+	}
+	old := d.input.Value()
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	if v := d.input.Value(); v != old {
+		cmd = tea.Batch(cmd, send(ChangeMsg{ID: d.id, Value: v}))
+	}
+	return d, cmd
+}
+
+func (d Input) View(width int) string {
+	w := innerWidth(width)
+	in := d.input
+	in.SetWidth(w - len(in.Prompt) - 1)
+	help := "enter to accept, esc to cancel"
+	if len(d.options) > 0 {
+		help = fmt.Sprintf("[%s] tab to change, %s", d.options[d.option], help)
+	}
+	return render(
+		title.Width(w).Render(d.title),
+		in.View(),
+		hint.Render(help),
+	)
+}
@@ -0,0 +1,46 @@
+package dialog
+
+import (
+	"charm.land/lipgloss/v2"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Message is a dialog that shows a message until enter, escape or space is
+// pressed, and then sends a CloseMsg.
+type Message struct {
+	id    any
+	title string
+	text  string
+	style lipgloss.Style // Style of the title
+}
+
+// NewMessage creates a message dialog.
+func NewMessage(id any, heading, text string) Message {
+	return Message{id: id, title: heading, text: text, style: title}
+}
+
+// NewError creates a message dialog that shows an error.
+func NewError(err error) Message {
+	d := NewMessage(nil, "Error", err.Error())
+	d.style = errorTitle
+	return d
+}
+
+func (d Message) Update(msg tea.Msg) (Dialog, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyPressMsg); ok {
+		switch msg.String() {
+		case "enter", "esc", "space", "ctrl+c":
+			return nil, send(CloseMsg{ID: d.id})
+		}
+	}
+	return d, nil
+}
+
+func (d Message) View(width int) string {
+	w := innerWidth(width)
+	return render(
+		d.style.Width(w).Render(d.title),
+		lipgloss.NewStyle().Width(w).Render(d.text),
+		hint.Render("enter to close"),
+	)
+}
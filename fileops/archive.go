@@ -0,0 +1,362 @@
+package fileops
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ancientlore/hermit2/archivefs"
+)
+
+// SizeFS returns the total size and number of files in the file or folder
+// p of a file system, such as an archive. Progress is sent to r, which may
+// be nil.
+func SizeFS(ctx context.Context, fsys fs.FS, p string, r Reporter) (int64, int, error) {
+	r = reporter(r)
+	var (
+		bytes int64
+		files int
+	)
+	err := fs.WalkDir(fsys, p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		files++
+		r.Current(path)
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			bytes += info.Size()
+			r.Add(info.Size())
+		}
+		r.FileDone()
+		return nil
+	})
+	return bytes, files, err
+}
+
+// Extract copies the file or folder src of a file system, such as an
+// archive, into the folder dir on disk. If src is ".", the whole file system
+// is extracted into dir. Archives are read in one pass and their members
+// streamed to disk. Folders are merged with any that exist, but files are
+// never overwritten. Names that lead outside dir, symbolic links that point
+// outside it, and writes through links are refused, so that an archive
+// can't write elsewhere ("zip slip"). Errors are collected so that the rest
+// is still extracted. Progress is sent to r, which may be nil.
+func Extract(ctx context.Context, fsys fs.FS, src, dir string, r Reporter) error {
+	r = reporter(r)
+	dir = filepath.Clean(dir)
+	dst := dir
+	if src != "." {
+		dst = filepath.Join(dir, path.Base(src))
+	}
+	var errs []error
+	err := archivefs.Walk(fsys, src, func(m archivefs.Member) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := dst
+		if m.Name != "." {
+			if !filepath.IsLocal(filepath.FromSlash(m.Name)) {
+				errs = append(errs, fmt.Errorf("%s: unsafe name %q", src, m.Name))
+				return nil
+			}
+			p = filepath.Join(dst, filepath.FromSlash(m.Name))
+		}
+		if err := extractMember(ctx, m, p, dir, r); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Join(append(errs, err)...)
+}
+
+// extractMember extracts a member to p, which must be inside root.
+func extractMember(ctx context.Context, m archivefs.Member, p, root string, r Reporter) error {
+	if !within(p, root) {
+		return fmt.Errorf("%s leads outside %s", m.Name, root)
+	}
+	if err := makeParents(p, root); err != nil {
+		return err
+	}
+	switch mode := m.Info.Mode(); {
+	case mode.IsDir() && p == root:
+		// The target folder, made by the caller
+		return nil
+	case mode.IsDir():
+		if err := os.Mkdir(p, mode.Perm()|0700); err != nil {
+			// Folders are merged with ones that exist, but not with links to them
+			if fi, lerr := os.Lstat(p); lerr != nil || !fi.IsDir() {
+				return err
+			}
+		}
+		return nil
+	case mode&fs.ModeSymlink != 0:
+		r.Current(m.Name)
+		defer r.FileDone()
+		t := filepath.FromSlash(m.Link)
+		if filepath.IsAbs(t) || strings.HasPrefix(m.Link, "/") || !within(filepath.Join(filepath.Dir(p), t), root) {
+			return fmt.Errorf("link %s to %s leads outside %s", m.Name, m.Link, root)
+		}
+		return os.Symlink(t, p)
+	case mode.IsRegular():
+		r.Current(m.Name)
+		defer r.FileDone()
+		return extractFile(ctx, m, p, r)
+	}
+	return fmt.Errorf("cannot extract special file %q", m.Name)
+}
+
+// makeParents makes the folders between root and p that don't exist. It
+// refuses to go through anything that is not a folder, such as a link made
+// by an earlier member, so that nothing is written outside root.
+func makeParents(p, root string) error {
+	rel, err := filepath.Rel(root, filepath.Dir(p))
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	dir := root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, name)
+		if err := os.Mkdir(dir, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a folder", dir)
+		}
+	}
+	return nil
+}
+
+// extractFile extracts a regular file, keeping its permissions and
+// modification time.
+func extractFile(ctx context.Context, m archivefs.Member, dst string, r Reporter) error {
+	in, err := m.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, m.Info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, &progressReader{ctx: ctx, rdr: in, r: r})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	if m.Info.ModTime().IsZero() {
+		return nil
+	}
+	return os.Chtimes(dst, m.Info.ModTime(), m.Info.ModTime())
+}
+
+// archiveWriter adds files to a new archive.
+type archiveWriter interface {
+	// add adds the file at p to the archive under name.
+	add(ctx context.Context, name, p string, info fs.FileInfo, r Reporter) error
+	Close() error
+}
+
+// Archive creates the archive dst from the named files and folders in dir.
+// The type of archive is chosen by its extension: .zip or .jar for zip,
+// .tar, or .tar.gz or .tgz for tar compressed with gzip. Symbolic links are
+// stored as links. Progress is sent to r, which may be nil.
+func Archive(ctx context.Context, dir string, names []string, dst string, r Reporter) error {
+	r = reporter(r)
+	lower := strings.ToLower(dst)
+	var newWriter func(io.Writer) archiveWriter
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		newWriter = func(w io.Writer) archiveWriter { return zipWriter{zip.NewWriter(w)} }
+	case strings.HasSuffix(lower, ".tar"):
+		newWriter = func(w io.Writer) archiveWriter { return tarWriter{tw: tar.NewWriter(w)} }
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		newWriter = func(w io.Writer) archiveWriter {
+			gz := gzip.NewWriter(w)
+			return tarWriter{tw: tar.NewWriter(gz), gz: gz}
+		}
+	default:
+		return fmt.Errorf("%s must end in .zip, .jar, .tar, .tar.gz or .tgz", filepath.Base(dst))
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	aw := newWriter(out)
+	err = func() error {
+		for _, name := range names {
+			err := filepath.WalkDir(filepath.Join(dir, name), func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				// The archive may be inside a folder it holds
+				if p == dst {
+					return nil
+				}
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					return err
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				return aw.add(ctx, filepath.ToSlash(rel), p, info, r)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return aw.Close()
+	}()
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// zipWriter writes a zip archive. Files are compressed with deflate.
+type zipWriter struct {
+	w *zip.Writer
+}
+
+func (z zipWriter) add(ctx context.Context, name, p string, info fs.FileInfo, r Reporter) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	switch {
+	case info.IsDir():
+		hdr.Name += "/"
+		_, err = z.w.CreateHeader(hdr)
+		return err
+	case info.Mode()&fs.ModeSymlink != 0:
+		// Zip archives keep the destination of a link as its contents
+		r.Current(p)
+		target, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+		w, err := z.w.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, filepath.ToSlash(target))
+		r.FileDone()
+		return err
+	case info.Mode().IsRegular():
+		r.Current(p)
+		hdr.Method = zip.Deflate
+		w, err := z.w.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		err = copyFrom(ctx, w, p, r)
+		r.FileDone()
+		return err
+	}
+	return fmt.Errorf("cannot archive special file %q", p)
+}
+
+func (z zipWriter) Close() error {
+	return z.w.Close()
+}
+
+// tarWriter writes a tar archive, compressed with gzip if gz is set.
+type tarWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (t tarWriter) add(ctx context.Context, name, p string, info fs.FileInfo, r Reporter) error {
+	var link string
+	switch {
+	case info.IsDir():
+		name += "/"
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+		link = filepath.ToSlash(target)
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("cannot archive special file %q", p)
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		return t.tw.WriteHeader(hdr)
+	}
+	r.Current(p)
+	err = t.tw.WriteHeader(hdr)
+	if err == nil && info.Mode().IsRegular() {
+		err = copyFrom(ctx, t.tw, p, r)
+	}
+	r.FileDone()
+	return err
+}
+
+func (t tarWriter) Close() error {
+	err := t.tw.Close()
+	if t.gz != nil {
+		err = errors.Join(err, t.gz.Close())
+	}
+	return err
+}
+
+// copyFrom copies the contents of the file at p to w.
+func copyFrom(ctx context.Context, w io.Writer, p string, r Reporter) error {
+	in, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(w, &progressReader{ctx: ctx, rdr: in, r: r})
+	return err
+}
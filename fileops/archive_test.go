@@ -0,0 +1,224 @@
+package fileops
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ancientlore/hermit2/archivefs"
+)
+
+// member is an entry of a test archive.
+type member struct {
+	name string
+	link string // Destination, for a symbolic link
+	hard bool   // Whether link is a hard link
+	body string
+}
+
+// writeArchive writes the members to an archive named by its extension.
+func writeArchive(t *testing.T, file string, members []member) {
+	t.Helper()
+	var buf bytes.Buffer
+	switch filepath.Ext(file) {
+	case ".zip":
+		zw := zip.NewWriter(&buf)
+		for _, m := range members {
+			hdr := &zip.FileHeader{Name: m.name, Method: zip.Deflate}
+			body := m.body
+			switch {
+			case m.link != "":
+				hdr.SetMode(fs.ModeSymlink | 0o777)
+				body = m.link
+			case m.name[len(m.name)-1] == '/':
+				hdr.SetMode(fs.ModeDir | 0o755)
+			default:
+				hdr.SetMode(0o644)
+			}
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(body))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		var gz *gzip.Writer
+		tw := tar.NewWriter(&buf)
+		if filepath.Ext(file) == ".tgz" {
+			gz = gzip.NewWriter(&buf)
+			tw = tar.NewWriter(gz)
+		}
+		for _, m := range members {
+			hdr := &tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.body)), Typeflag: tar.TypeReg}
+			switch {
+			case m.hard:
+				hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeLink, m.link, 0
+			case m.link != "":
+				hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, m.link, 0
+			case m.name[len(m.name)-1] == '/':
+				hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			tw.Write([]byte(m.body))
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if gz != nil {
+			gz.Close()
+		}
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// list returns the paths of everything under dir, with links marked by ->.
+func list(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		name := filepath.ToSlash(rel)
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, _ := os.Readlink(p)
+			name += " -> " + filepath.ToSlash(target)
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// extract extracts the whole of an archive in base into base/target.
+func extract(t *testing.T, base, name string) error {
+	t.Helper()
+	afs, closer, err := archivefs.Open(os.DirFS(base), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	target := filepath.Join(base, "target")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return Extract(context.Background(), afs, ".", target, nil)
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs symbolic links")
+	}
+	for _, ext := range []string{".zip", ".tar", ".tgz"} {
+		t.Run(ext, func(t *testing.T) {
+			base := t.TempDir()
+			outside := filepath.Join(base, "outside")
+			if err := os.Mkdir(outside, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			members := []member{
+				{name: "../dotdot.txt", body: "x"},
+				{name: "sub/../../../up.txt", body: "x"},
+				{name: "/abs.txt", body: "x"},
+				{name: filepath.ToSlash(outside) + "/abspath.txt", body: "x"},
+				{name: "out", link: "../outside"},
+				{name: "out/through.txt", body: "x"},
+				{name: "abs", link: outside},
+				{name: "abs/through.txt", body: "x"},
+				{name: "deep/up", link: "../../outside"},
+				{name: "deep/up/through.txt", body: "x"},
+				{name: "sub/", body: ""},
+				{name: "in", link: "sub"},
+				{name: "in/through.txt", body: "x"},
+				{name: "sub/ok.txt", body: "ok"},
+			}
+			if ext != ".zip" {
+				members = append(members, member{name: "hard", link: "../outside/h", hard: true})
+			}
+			archive := "a" + ext
+			writeArchive(t, filepath.Join(base, archive), members)
+
+			if err := extract(t, base, archive); err == nil {
+				t.Error("no error for unsafe members")
+			}
+			if names := list(t, outside); len(names) > 0 {
+				t.Errorf("written outside the target: %q", names)
+			}
+			for _, name := range list(t, base) {
+				if name != archive && name != "outside" && name != "target" && !strings.HasPrefix(name, "target/") {
+					t.Errorf("%s written beside the target", name)
+				}
+			}
+			got := list(t, filepath.Join(base, "target"))
+			for _, want := range []string{"dotdot.txt", "up.txt", "abs.txt", "sub/ok.txt", "in -> sub"} {
+				if !slices.Contains(got, want) {
+					t.Errorf("%s not extracted; got %q", want, got)
+				}
+			}
+			for _, name := range got {
+				// Links that lead outside are not made, and nothing is
+				// written through the link to sub
+				if strings.HasPrefix(name, "out ->") || strings.HasPrefix(name, "abs ->") || name == "sub/through.txt" {
+					t.Errorf("%s extracted", name)
+				}
+			}
+			if b, _ := os.ReadFile(filepath.Join(base, "target", "sub", "ok.txt")); string(b) != "ok" {
+				t.Errorf("sub/ok.txt = %q, want ok", b)
+			}
+		})
+	}
+}
+
+func TestExtractMember(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar", ".tgz"} {
+		t.Run(ext, func(t *testing.T) {
+			base := t.TempDir()
+			archive := "a" + ext
+			writeArchive(t, filepath.Join(base, archive), []member{
+				{name: "a/b/c.txt", body: "c"},
+				{name: "a/d.txt", body: "d"},
+				{name: "e.txt", body: "e"},
+			})
+			afs, closer, err := archivefs.Open(os.DirFS(base), archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closer.Close()
+			target := filepath.Join(base, "target")
+			os.Mkdir(target, 0o755)
+			if err := Extract(context.Background(), afs, "a/b", target, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := Extract(context.Background(), afs, "e.txt", target, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := list(t, target), []string{"b", "b/c.txt", "e.txt"}; !slices.Equal(got, want) {
+				t.Errorf("extracted %q, want %q", got, want)
+			}
+			// Files are never overwritten
+			if err := Extract(context.Background(), afs, "e.txt", target, nil); err == nil {
+				t.Error("no error when extracting over a file")
+			}
+		})
+	}
+}
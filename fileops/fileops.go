@@ -0,0 +1,283 @@
+// Package fileops implements the file operations that the browser performs
+// on selected entries. Folders are handled recursively, and operations stop
+// early when their context is canceled.
+package fileops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reporter receives progress from file operations.
+type Reporter interface {
+	Current(name string) // Called with each file as it is started
+	Add(bytes int64)     // Called as bytes are copied
+	FileDone()           // Called as each file is completed
+}
+
+// nopReporter ignores progress.
+type nopReporter struct{}
+
+func (nopReporter) Current(string) {}
+func (nopReporter) Add(int64)      {}
+func (nopReporter) FileDone()      {}
+
+func reporter(r Reporter) Reporter {
+	if r == nil {
+		return nopReporter{}
+	}
+	return r
+}
+
+// Size returns the total size and number of files in the file or folder p.
+// Progress is sent to r, which may be nil.
+func Size(ctx context.Context, p string, r Reporter) (int64, int, error) {
+	r = reporter(r)
+	var (
+		bytes int64
+		files int
+	)
+	err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		files++
+		r.Current(path)
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			bytes += info.Size()
+			r.Add(info.Size())
+		}
+		r.FileDone()
+		return nil
+	})
+	return bytes, files, err
+}
+
+// Copy copies the file or folder src to dst, which must not exist.
+// Symbolic links are copied as links. Progress is sent to r, which may be nil.
+// If anything can't be copied, or the copy is canceled, nothing is left at
+// dst; the error lists every entry that failed.
+func Copy(ctx context.Context, src, dst string, r Reporter) error {
+	return copyAll(ctx, src, dst, reporter(r))
+}
+
+// copyAll copies src to dst, removing the partial copy if it fails.
+func copyAll(ctx context.Context, src, dst string, r Reporter) error {
+	err := copyAny(ctx, src, dst, r)
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		// dst did not exist, so anything there now was copied
+		if rerr := removeCopy(dst); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+	}
+	return err
+}
+
+// removeCopy removes a partial copy, first making its folders writable
+// in case their permissions were copied.
+func removeCopy(p string) error {
+	filepath.WalkDir(p, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(p, 0700)
+		}
+		return nil
+	})
+	return os.RemoveAll(p)
+}
+
+func copyAny(ctx context.Context, src, dst string, r Reporter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return &fs.PathError{Op: "copy", Path: dst, Err: fs.ErrExist}
+	}
+
+	switch {
+	case info.IsDir():
+		if within(dst, src) {
+			return fmt.Errorf("cannot copy folder %q into itself", src)
+		}
+		return copyDir(ctx, src, dst, info, r)
+	case info.Mode()&fs.ModeSymlink != 0:
+		r.Current(src)
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		err = os.Symlink(target, dst)
+		r.FileDone()
+		return err
+	case info.Mode().IsRegular():
+		r.Current(src)
+		err = copyFile(ctx, src, dst, info, r)
+		r.FileDone()
+		return err
+	}
+	return fmt.Errorf("cannot copy special file %q", src)
+}
+
+// copyDir copies a folder and its contents, going on past entries that
+// fail unless the copy is canceled.
+func copyDir(ctx context.Context, src, dst string, info fs.FileInfo, r Reporter) error {
+	err := os.Mkdir(dst, info.Mode().Perm()|0700)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		err = copyAny(ctx, filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), r)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if u, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = append(errs, u.Unwrap()...)
+		} else if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	// Apply the original permissions last in case they prevent writing.
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// copyFile copies a regular file, keeping its permissions and modification time.
+func copyFile(ctx context.Context, src, dst string, info fs.FileInfo, r Reporter) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, &progressReader{ctx: ctx, rdr: in, r: r})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// progressReader reports bytes read and stops when the context is canceled.
+type progressReader struct {
+	ctx context.Context
+	rdr io.Reader
+	r   Reporter
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.rdr.Read(b)
+	p.r.Add(int64(n))
+	return n, err
+}
+
+// Move moves or renames src to dst, which must not exist. When a rename
+// is not possible because dst is on another device, src is copied and then
+// deleted. If the copy fails or is canceled, it is removed and src is kept.
+func Move(ctx context.Context, src, dst string, r Reporter) error {
+	r = reporter(r)
+	if _, err := os.Lstat(dst); err == nil {
+		return &fs.PathError{Op: "move", Path: dst, Err: fs.ErrExist}
+	}
+	if info, err := os.Lstat(src); err == nil && info.IsDir() && within(dst, src) {
+		return fmt.Errorf("cannot move folder %q into itself", src)
+	}
+	r.Current(src)
+	err := os.Rename(src, dst)
+	if err == nil {
+		r.FileDone()
+		return nil
+	}
+	// Only a move to another device is done by copying; other errors,
+	// such as a lack of permission, mean the move fails
+	if !crossDevice(err) {
+		return err
+	}
+	if err := copyAll(ctx, src, dst, r); err != nil {
+		return err
+	}
+	return Delete(ctx, src, nil)
+}
+
+// Delete removes the file or folder at p, including any contents.
+// Progress is sent to r, which may be nil.
+func Delete(ctx context.Context, p string, r Reporter) error {
+	return deleteAny(ctx, p, reporter(r))
+}
+
+func deleteAny(ctx context.Context, p string, r Reporter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := deleteAny(ctx, filepath.Join(p, entry.Name()), r); err != nil {
+				return err
+			}
+		}
+	} else {
+		r.Current(p)
+	}
+	err = os.Remove(p)
+	if !info.IsDir() {
+		r.FileDone()
+	}
+	return err
+}
+
+// MakeDir creates a new folder.
+func MakeDir(p string) error {
+	return os.Mkdir(p, 0755)
+}
+
+// within reports whether p is the same as or inside the folder dir.
+func within(p, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
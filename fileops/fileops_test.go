@@ -0,0 +1,76 @@
+package fileops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tree creates files under dir, with names ending in / made as folders.
+func tree(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		var err error
+		if strings.HasSuffix(name, "/") {
+			err = os.MkdirAll(p, 0o755)
+		} else {
+			err = os.WriteFile(p, []byte(name), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	tree(t, src, "a/", "a/b.txt", "c.txt")
+	if err := os.Chmod(filepath.Join(src, "a"), 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(src, "a"), 0o755) })
+	if err := Copy(context.Background(), src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(t, dst), []string{"a", "a/b.txt", "c.txt"}; !slices.Equal(got, want) {
+		t.Errorf("copied %q, want %q", got, want)
+	}
+	if err := Copy(context.Background(), src, dst, nil); !errors.Is(err, os.ErrExist) {
+		t.Errorf("copy over a folder: %v, want ErrExist", err)
+	}
+	if got := list(t, dst); len(got) != 3 {
+		t.Errorf("failed copy changed the folder it would overwrite: %q", got)
+	}
+}
+
+func TestCopyCanceled(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	tree(t, src, "a/", "a/b.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &cancelReporter{cancel: cancel}
+	if err := Copy(ctx, src, dst, r); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled copy: %v, want context.Canceled", err)
+	}
+	if _, err := os.Lstat(dst); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial copy left behind: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a", "b.txt")); err != nil {
+		t.Errorf("source changed: %v", err)
+	}
+}
+
+// cancelReporter cancels an operation when the first file is started.
+type cancelReporter struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancelReporter) Current(string) { c.cancel() }
+func (c *cancelReporter) Add(int64)      {}
+func (c *cancelReporter) FileDone()      {}
@@ -0,0 +1,43 @@
+//go:build !windows
+
+package fileops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCopyFails(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	tree(t, src, "a/", "a/b.txt", "c/", "z.txt")
+	// A permission copied to a folder must not stop the copy being removed
+	if err := os.Chmod(filepath.Join(src, "a"), 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(src, "a"), 0o755) })
+	for _, name := range []string{"c/pipe1", "pipe2"} {
+		if err := syscall.Mkfifo(filepath.Join(src, name), 0o644); err != nil {
+			t.Skip("no named pipes:", err)
+		}
+	}
+
+	err := Copy(context.Background(), src, dst, nil)
+	if err == nil {
+		t.Fatal("no error copying named pipes")
+	}
+	// Every entry that fails is reported
+	for _, name := range []string{"pipe1", "pipe2"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q doesn't mention %s", err, name)
+		}
+	}
+	if _, err := os.Lstat(dst); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("partial copy left behind: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package fileops
+
+import (
+	"errors"
+	"syscall"
+)
+
+// crossDevice reports whether a rename failed because the source and
+// destination are on different devices.
+func crossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package fileops
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when a file is
+// moved to another drive.
+const errorNotSameDevice syscall.Errno = 17
+
+// crossDevice reports whether a rename failed because the source and
+// destination are on different drives.
+func crossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,389 @@
+// Package jobs runs long operations, such as copying files, in the
+// background. Jobs report their progress to the user interface as
+// Bubble Tea messages and can be canceled through their context.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// progressInterval limits how often progress messages are sent for a job.
+const progressInterval = 100 * time.Millisecond
+
+// State is the state of a job.
+type State int
+
+// Job states.
+const (
+	Running State = iota
+	Finished
+	Failed
+	Canceled
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Finished:
+		return "finished"
+	case Failed:
+		return "failed"
+	case Canceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// Func is the work done by a job. It should stop when ctx is canceled and
+// report progress through the job.
+type Func func(ctx context.Context, j *Job) error
+
+// ProgressMsg is sent while a job makes progress.
+type ProgressMsg struct {
+	Job *Job
+}
+
+// Broadcast marks the message as one for every screen, not only the one
+// shown.
+func (ProgressMsg) Broadcast() {}
+
+// DoneMsg is sent when a job ends.
+type DoneMsg struct {
+	Job *Job
+}
+
+// Broadcast marks the message as one for every screen, not only the one
+// shown.
+func (DoneMsg) Broadcast() {}
+
+// Progress describes how far along a job is.
+type Progress struct {
+	Bytes      int64  // Bytes processed so far
+	TotalBytes int64  // Total bytes to process, or 0 if unknown
+	Files      int    // Files processed so far
+	TotalFiles int    // Total files to process, or 0 if unknown
+	Current    string // Item being processed
+}
+
+// Job is a unit of background work.
+type Job struct {
+	ID      int      // Sequence number of the job
+	Name    string   // Description of the job
+	Folders []string // Folders on disk the job changes
+
+	mgr      *Manager
+	cancel   context.CancelFunc
+	started  time.Time
+	mu       sync.Mutex
+	progress Progress
+	state    State
+	err      error
+	ended    time.Time
+	lastSent time.Time
+	reported bool // Whether the errors have been shown
+}
+
+// Progress returns the current progress of the job.
+func (j *Job) Progress() Progress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// State returns the state of the job.
+func (j *Job) State() State {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// Err returns the error the job ended with, if any.
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// MarkReported records that the errors of the job have been shown. It
+// returns false if they already were.
+func (j *Job) MarkReported() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.reported {
+		return false
+	}
+	j.reported = true
+	return true
+}
+
+// Elapsed returns how long the job has been running, or how long it ran.
+func (j *Job) Elapsed() time.Duration {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != Running {
+		return j.ended.Sub(j.started)
+	}
+	return time.Since(j.started)
+}
+
+// ETA estimates the time remaining from the rate of progress so far.
+// It returns -1 when no estimate can be made.
+func (j *Job) ETA() time.Duration {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	done, total := j.progress.fraction()
+	if j.state != Running || done <= 0 || total <= 0 {
+		return -1
+	}
+	elapsed := time.Since(j.started)
+	return time.Duration(float64(elapsed) * (total - done) / done)
+}
+
+// Percent returns the completed fraction of the job from 0 to 1, or -1
+// if the total is unknown.
+func (j *Job) Percent() float64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	done, total := j.progress.fraction()
+	if total <= 0 {
+		return -1
+	}
+	if done > total {
+		return 1
+	}
+	return done / total
+}
+
+// fraction returns the work done and the total, measured in bytes when
+// known and in files otherwise.
+func (p Progress) fraction() (float64, float64) {
+	if p.TotalBytes > 0 {
+		return float64(p.Bytes), float64(p.TotalBytes)
+	}
+	return float64(p.Files), float64(p.TotalFiles)
+}
+
+// Cancel asks the job to stop.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// SetTotal sets the amount of work the job expects to do.
+func (j *Job) SetTotal(bytes int64, files int) {
+	j.update(func(p *Progress) {
+		p.TotalBytes = bytes
+		p.TotalFiles = files
+	})
+}
+
+// Current records the item being processed.
+func (j *Job) Current(name string) {
+	j.update(func(p *Progress) { p.Current = name })
+}
+
+// Add records that bytes were processed.
+func (j *Job) Add(bytes int64) {
+	j.update(func(p *Progress) { p.Bytes += bytes })
+}
+
+// FileDone records that a file was processed.
+func (j *Job) FileDone() {
+	j.update(func(p *Progress) { p.Files++ })
+}
+
+// update changes the progress and notifies the user interface,
+// no more often than progressInterval.
+func (j *Job) update(f func(p *Progress)) {
+	j.mu.Lock()
+	f(&j.progress)
+	send := time.Since(j.lastSent) >= progressInterval
+	if send {
+		j.lastSent = time.Now()
+	}
+	j.mu.Unlock()
+	if send {
+		j.mgr.send(ProgressMsg{Job: j})
+	}
+}
+
+// Status returns a one-line summary of the job for display, with a
+// progress bar of the given width while it is running.
+func (j *Job) Status(barWidth int) string {
+	p := j.Progress()
+	state := j.State()
+	if state != Running {
+		s := fmt.Sprintf("%s: %s", j.Name, state)
+		if err := j.Err(); err != nil && state == Failed {
+			s += ": " + strings.ReplaceAll(err.Error(), "\n", "; ")
+		} else if p.Files > 0 {
+			s += fmt.Sprintf(" (%d files, %s)", p.Files, FormatBytes(p.Bytes))
+		}
+		return s
+	}
+
+	var b strings.Builder
+	pct := j.Percent()
+	if pct >= 0 {
+		fill := int(pct * float64(barWidth))
+		b.WriteString(strings.Repeat("█", fill))
+		b.WriteString(strings.Repeat("░", barWidth-fill))
+		fmt.Fprintf(&b, " %3.0f%% ", pct*100)
+	}
+	b.WriteString(j.Name)
+	if p.TotalFiles > 0 {
+		fmt.Fprintf(&b, "  %d/%d files", p.Files, p.TotalFiles)
+	} else if p.Files > 0 {
+		fmt.Fprintf(&b, "  %d files", p.Files)
+	}
+	if p.TotalBytes > 0 {
+		fmt.Fprintf(&b, "  %s/%s", FormatBytes(p.Bytes), FormatBytes(p.TotalBytes))
+	} else if p.Bytes > 0 {
+		fmt.Fprintf(&b, "  %s", FormatBytes(p.Bytes))
+	}
+	if eta := j.ETA(); eta >= 0 {
+		fmt.Fprintf(&b, "  ETA %s", eta.Round(time.Second))
+	}
+	if p.Current != "" {
+		fmt.Fprintf(&b, "  %s", p.Current)
+	}
+	return b.String()
+}
+
+// FormatBytes formats a byte count using binary units.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// maxEnded is the most jobs that have ended that a Manager keeps.
+const maxEnded = 100
+
+// Manager starts jobs and keeps track of them. Only the most recent jobs
+// that have ended are kept.
+type Manager struct {
+	mu     sync.Mutex
+	jobs   []*Job
+	nextID int
+	sender func(tea.Msg)
+}
+
+// NewManager creates a job manager.
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// SetSender sets the function used to deliver messages to the user
+// interface, normally the Send method of the tea.Program.
+func (m *Manager) SetSender(send func(tea.Msg)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sender = send
+}
+
+func (m *Manager) send(msg tea.Msg) {
+	m.mu.Lock()
+	send := m.sender
+	m.mu.Unlock()
+	if send != nil {
+		send(msg)
+	}
+}
+
+// Start runs f in a new goroutine as a job with the given name, which
+// changes the given folders. A DoneMsg is sent when it ends.
+func (m *Manager) Start(name string, folders []string, f Func) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	m.nextID++
+	j := &Job{
+		ID:      m.nextID,
+		Name:    name,
+		Folders: folders,
+		mgr:     m,
+		cancel:  cancel,
+		started: time.Now(),
+	}
+	m.jobs = append(m.jobs, j)
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := f(ctx, j)
+		j.mu.Lock()
+		j.err = err
+		j.ended = time.Now()
+		switch {
+		case errors.Is(err, context.Canceled):
+			j.state = Canceled
+		case err != nil:
+			j.state = Failed
+		default:
+			j.state = Finished
+		}
+		j.mu.Unlock()
+		m.prune()
+		m.send(DoneMsg{Job: j})
+	}()
+	return j
+}
+
+// prune drops the oldest jobs that have ended, beyond maxEnded.
+func (m *Manager) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ended := 0
+	for _, j := range m.jobs {
+		if j.State() != Running {
+			ended++
+		}
+	}
+	m.jobs = slices.DeleteFunc(m.jobs, func(j *Job) bool {
+		if ended > maxEnded && j.State() != Running {
+			ended--
+			return true
+		}
+		return false
+	})
+}
+
+// Jobs returns all jobs, most recent first.
+func (m *Manager) Jobs() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := make([]*Job, len(m.jobs))
+	for i, j := range m.jobs {
+		a[len(a)-1-i] = j
+	}
+	return a
+}
+
+// Running returns the jobs that have not ended, oldest first.
+func (m *Manager) Running() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var a []*Job
+	for _, j := range m.jobs {
+		if j.State() == Running {
+			a = append(a, j)
+		}
+	}
+	return a
+}
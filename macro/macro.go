@@ -0,0 +1,192 @@
+// Package macro expands Hermit command macros. A macro is a command line
+// containing the following substitutions:
+//
+//	!f  the file at the cursor
+//	!m  the selected files, separated by $HERMIT_LISTSEP
+//	!q  the selected files, each quoted for the shell
+//	!d  the current folder
+//	!p  a value the user is prompted for, once per occurrence
+//	!!  a literal "!"
+//
+// Any other use of "!" is left as is. Path separators in substituted values
+// are replaced with $HERMIT_DIRSEP when it is set.
+//
+// The file from !f and the folder from !d are quoted for the shell, as are
+// the names from !q. The names from !m are inserted as they are, and so are
+// names wrapped in $HERMIT_LISTQUOTE when it is set, so Expand refuses
+// those that contain characters the shell would interpret, such as "$",
+// "`" or ";".
+package macro
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Context holds the values substituted into a macro.
+type Context struct {
+	Dir      string   // Current folder
+	File     string   // Name of the file at the cursor
+	Selected []string // Names of the selected files
+	Prompts  []string // Values entered for each !p, in order
+	Shell    string   // Shell that runs the command line, which decides how !q quotes
+}
+
+// part is a literal piece of a macro or a substitution code.
+type part struct {
+	text string // Literal text, when code is 0
+	code byte   // Substitution code: 'f', 'm', 'q', 'd' or 'p'
+}
+
+// Macro is a parsed command macro.
+type Macro struct {
+	parts []part
+}
+
+// Parse parses a command macro.
+func Parse(s string) *Macro {
+	var (
+		m   Macro
+		lit strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '!' && i+1 < len(s) {
+			switch c := s[i+1]; c {
+			case 'f', 'm', 'q', 'd', 'p':
+				if lit.Len() > 0 {
+					m.parts = append(m.parts, part{text: lit.String()})
+					lit.Reset()
+				}
+				m.parts = append(m.parts, part{code: c})
+				i++
+				continue
+			case '!':
+				lit.WriteByte('!')
+				i++
+				continue
+			}
+		}
+		lit.WriteByte(s[i])
+	}
+	if lit.Len() > 0 {
+		m.parts = append(m.parts, part{text: lit.String()})
+	}
+	return &m
+}
+
+// Prompts returns the number of values the user must be prompted for.
+func (m *Macro) Prompts() int {
+	n := 0
+	for _, p := range m.parts {
+		if p.code == 'p' {
+			n++
+		}
+	}
+	return n
+}
+
+// Expand returns the command line with the substitutions made. Missing
+// prompt values are replaced with empty strings. It fails if a name that
+// is not quoted for the shell contains characters the shell interprets.
+func (m *Macro) Expand(ctx Context) (string, error) {
+	var (
+		b      strings.Builder
+		prompt int
+	)
+	listSep := env("HERMIT_LISTSEP", " ")
+	quote := func(s string) (string, error) { return Quote(ctx.Shell, s), nil }
+	if q, ok := os.LookupEnv("HERMIT_LISTQUOTE"); ok {
+		quote = func(s string) (string, error) {
+			if !plain(s, " ") || strings.Contains(s, q) {
+				return "", fmt.Errorf("can't quote %q with HERMIT_LISTQUOTE; unset it to have !q quote for the shell", s)
+			}
+			return q + s + q, nil
+		}
+	}
+	for _, p := range m.parts {
+		switch p.code {
+		case 0:
+			b.WriteString(p.text)
+		case 'f':
+			b.WriteString(Quote(ctx.Shell, dirSep(ctx.File)))
+		case 'd':
+			b.WriteString(Quote(ctx.Shell, dirSep(ctx.Dir)))
+		case 'm', 'q':
+			for i, name := range ctx.Selected {
+				if i > 0 {
+					b.WriteString(listSep)
+				}
+				name = dirSep(name)
+				if p.code == 'q' {
+					q, err := quote(name)
+					if err != nil {
+						return "", err
+					}
+					b.WriteString(q)
+				} else if plain(name, "") {
+					b.WriteString(name)
+				} else {
+					return "", fmt.Errorf("can't pass %q to the shell with !m; use !q", name)
+				}
+			}
+		case 'p':
+			if prompt < len(ctx.Prompts) {
+				b.WriteString(ctx.Prompts[prompt])
+			}
+			prompt++
+		}
+	}
+	return b.String(), nil
+}
+
+// plain returns whether s has only letters, digits, characters from extra
+// and punctuation that no shell interprets.
+func plain(s, extra string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-+=:,@/"+extra, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Quote quotes s as a single argument for a command line run by shell,
+// so that none of its characters are interpreted. The shell's name decides
+// the rules: cmd.exe and PowerShell have their own, and any other shell is
+// taken to be a POSIX shell.
+func Quote(shell, s string) string {
+	name := strings.ToLower(path.Base(strings.ReplaceAll(shell, `\`, "/")))
+	name = strings.TrimSuffix(name, ".exe")
+	switch name {
+	case "cmd":
+		// Quotes stop cmd.exe from reading & | < > ^ and spaces, but not %,
+		// which is escaped with ^ outside the quotes
+		s = strings.ReplaceAll(s, `"`, `""`)
+		s = strings.ReplaceAll(s, "%", `"^%"`)
+		return `"` + s + `"`
+	case "powershell", "pwsh":
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// env returns the value of an environment variable, or def if it is not set.
+func env(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+// dirSep replaces path separators with $HERMIT_DIRSEP, if set.
+func dirSep(s string) string {
+	sep, ok := os.LookupEnv("HERMIT_DIRSEP")
+	if !ok || sep == string(filepath.Separator) {
+		return s
+	}
+	return strings.ReplaceAll(s, string(filepath.Separator), sep)
+}
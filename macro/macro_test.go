@@ -0,0 +1,159 @@
+package macro
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// unsafeNames are file names that a shell would act on if they were not
+// quoted.
+var unsafeNames = []string{
+	"plain.txt",
+	"with space",
+	"$(touch pwned)",
+	"`touch pwned`",
+	`dq"uote`,
+	"it's",
+	"semi;colon && pipe | amp &",
+	"glob*?[x]",
+	"$HOME ${PATH} ~",
+	"back\\slash",
+	"new\nline",
+	"-dash",
+}
+
+// clearEnv unsets the variables that change how macros expand.
+func clearEnv(t *testing.T) {
+	for _, name := range []string{"HERMIT_LISTSEP", "HERMIT_LISTQUOTE", "HERMIT_DIRSEP"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	clearEnv(t)
+	ctx := Context{
+		Dir:      "/work",
+		File:     "a.txt",
+		Selected: []string{"b-c", "d"},
+		Prompts:  []string{"one"},
+		Shell:    "/bin/sh",
+	}
+	tests := []struct {
+		macro string
+		want  string
+	}{
+		{"cat !f", "cat 'a.txt'"},
+		{"ls !m", "ls b-c d"},
+		{"ls !q", "ls 'b-c' 'd'"},
+		{"cd !d && echo !p !p", "cd '/work' && echo one "},
+		{"echo !! !x", "echo ! !x"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.macro).Expand(ctx)
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v, want %q", tt.macro, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandUnsafe(t *testing.T) {
+	clearEnv(t)
+	for _, name := range unsafeNames {
+		if name == "plain.txt" || name == "-dash" {
+			continue // Nothing the shell interprets
+		}
+		ctx := Context{Selected: []string{"ok", name}, Shell: "/bin/sh"}
+		if got, err := Parse("ls !m").Expand(ctx); err == nil {
+			t.Errorf("!m of %q = %q, want an error", name, got)
+		}
+		if _, err := Parse("ls !q").Expand(ctx); err != nil {
+			t.Errorf("!q of %q: %v", name, err)
+		}
+	}
+}
+
+func TestExpandListQuote(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("HERMIT_LISTQUOTE", `"`)
+	got, err := Parse("ls !q").Expand(Context{Selected: []string{"a", "b c"}, Shell: "/bin/sh"})
+	if want := `ls "a" "b c"`; err != nil || got != want {
+		t.Errorf("Expand = %q, %v, want %q", got, err, want)
+	}
+	for _, name := range []string{`dq"uote`, "$(touch pwned)", "it's"} {
+		if got, err := Parse("ls !q").Expand(Context{Selected: []string{name}, Shell: "/bin/sh"}); err == nil {
+			t.Errorf("Expand of %q = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		shell, s, want string
+	}{
+		{"/bin/sh", "it's", `'it'\''s'`},
+		{"/bin/bash", "$(x)", `'$(x)'`},
+		{"", "a b", `'a b'`},
+		{`C:\Windows\System32\cmd.exe`, "a & b", `"a & b"`},
+		{"cmd.exe", "100%", `"100"^%""`},
+		{"CMD", `a"b`, `"a""b"`},
+		{"pwsh", "it's $x", `'it''s $x'`},
+		{`C:\Program Files\PowerShell\powershell.exe`, "a'b", `'a''b'`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.shell, tt.s); got != tt.want {
+			t.Errorf("Quote(%q, %q) = %q, want %q", tt.shell, tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestQuoteShell runs names with shell metacharacters through a POSIX
+// shell as !q, !f and !d, checking that each arrives as one argument,
+// unchanged, and that nothing in them is run.
+func TestQuoteShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh:", err)
+	}
+	clearEnv(t)
+	t.Setenv("HERMIT_LISTSEP", " ")
+	dir := t.TempDir()
+
+	// run expands a macro and returns the arguments the shell saw
+	run := func(macro string, ctx Context) []string {
+		t.Helper()
+		ctx.Shell = sh
+		line, err := Parse(macro).Expand(ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", macro, err)
+		}
+		c := exec.Command(sh, "-c", line)
+		c.Dir = dir
+		out, err := c.Output()
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	}
+
+	got := run(`for a in !q; do printf '%s\0' "$a"; done`, Context{Selected: unsafeNames})
+	if strings.Join(got, "|") != strings.Join(unsafeNames, "|") {
+		t.Errorf("!q: shell saw %q, want %q", got, unsafeNames)
+	}
+	for _, name := range unsafeNames {
+		want := []string{name, "/dir/" + name}
+		got := run(`printf '%s\0' !f !d`, Context{File: name, Dir: "/dir/" + name})
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("!f !d: shell saw %q, want %q", got, want)
+		}
+	}
+	if _, err := os.Stat(dir + "/pwned"); err == nil {
+		t.Error("a command in a file name was run")
+	}
+}
@@ -0,0 +1,73 @@
+// Package nav defines the messages that screens use to navigate. They are
+// handled by the root model, which keeps the tabs of open screens.
+package nav
+
+import (
+	tea "charm.land/bubbletea/v2"
+)
+
+// PushMsg opens a screen over the current one.
+type PushMsg struct {
+	Model tea.Model
+}
+
+// Push returns a command that opens a screen over the current one.
+func Push(m tea.Model) tea.Cmd {
+	return func() tea.Msg {
+		return PushMsg{Model: m}
+	}
+}
+
+// PopMsg closes the current screen and returns to the one beneath it.
+// The last screen is never closed.
+type PopMsg struct{}
+
+// Pop is a command that closes the current screen.
+func Pop() tea.Msg {
+	return PopMsg{}
+}
+
+// QuitMsg closes all screens and exits.
+type QuitMsg struct{}
+
+// Quit is a command that closes all screens and exits.
+func Quit() tea.Msg {
+	return QuitMsg{}
+}
+
+// NewTabMsg opens a screen in a new tab after the current one, and shows it.
+type NewTabMsg struct {
+	Model tea.Model
+}
+
+// NewTab returns a command that opens a screen in a new tab.
+func NewTab(m tea.Model) tea.Cmd {
+	return func() tea.Msg {
+		return NewTabMsg{Model: m}
+	}
+}
+
+// CloseTabMsg closes the current tab and all of its screens. The last tab
+// is never closed.
+type CloseTabMsg struct{}
+
+// CloseTab is a command that closes the current tab.
+func CloseTab() tea.Msg {
+	return CloseTabMsg{}
+}
+
+// CycleTabMsg shows the tab Step places after the current one, wrapping
+// around at either end.
+type CycleTabMsg struct {
+	Step int
+}
+
+// NextTab is a command that shows the next tab.
+func NextTab() tea.Msg {
+	return CycleTabMsg{Step: 1}
+}
+
+// PrevTab is a command that shows the previous tab.
+func PrevTab() tea.Msg {
+	return CycleTabMsg{Step: -1}
+}
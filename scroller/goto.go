@@ -0,0 +1,81 @@
+package scroller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ancientlore/hermit2/dialog"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Locator is implemented by viewers that can find a position from a target
+// typed by the user, such as a line number or a byte offset.
+type Locator interface {
+	// Targets describes what can be typed, for the prompt.
+	Targets() string
+
+	// Locate returns the position of the target.
+	Locate(target string, width int) (int, error)
+}
+
+// gotoID asks for a target to go to.
+type gotoID struct{}
+
+// ParsePercent parses a percentage such as "50%". It returns false if s
+// is not a percentage.
+func ParsePercent(s string) (float64, bool, error) {
+	s, ok := strings.CutSuffix(strings.TrimSpace(s), "%")
+	if !ok {
+		return 0, false, nil
+	}
+	p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, true, fmt.Errorf("invalid percentage %q", s+"%")
+	}
+	return p, true, nil
+}
+
+// updateGoto handles the goto key and prompt. It returns false if the
+// message is not about going to a position.
+func (m *Model[T]) updateGoto(msg tea.Msg) (bool, tea.Cmd) {
+	l, ok := any(&m.Data).(Locator)
+	if !ok {
+		return false, nil
+	}
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		if key.Matches(msg, DefaultViewKeyMap.Goto) {
+			return true, dialog.Open(dialog.NewInput(gotoID{}, "Go to "+l.Targets()+":", ""))
+		}
+
+	case dialog.InputMsg:
+		if _, ok := msg.ID.(gotoID); ok {
+			if strings.TrimSpace(msg.Value) == "" {
+				return true, nil
+			}
+			pos, err := l.Locate(msg.Value, m.width)
+			if err != nil {
+				m.Status = err.Error()
+				return true, nil
+			}
+			m.SetCursor(pos)
+			m.center()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// center scrolls the view so that the cursor is in the middle, as far
+// as the ends of the data allow.
+func (m *Model[T]) center() {
+	m.offset = m.cursor - m.height/2
+	if last := m.Data.Len(m.width) - m.height; m.offset > last {
+		m.offset = last
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
@@ -1,7 +1,12 @@
 package scroller
 
-import "charm.land/bubbles/v2/key"
+import (
+	"strings"
 
+	"charm.land/bubbles/v2/key"
+)
+
+// KeyMap holds the key bindings common to all scrolling views.
 type KeyMap struct {
 	Up       key.Binding
 	Down     key.Binding
@@ -47,3 +52,112 @@ var DefaultKeyMap = KeyMap{
 		key.WithHelp("ctrl+c", "exit Hermit"),
 	),
 }
+
+// Bindings returns the bindings in the key map by action name.
+func (km *KeyMap) Bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"Up":       &km.Up,
+		"Down":     &km.Down,
+		"Left":     &km.Left,
+		"PageUp":   &km.PageUp,
+		"PageDown": &km.PageDown,
+		"Home":     &km.Home,
+		"End":      &km.End,
+		"Quit":     &km.Quit,
+	}
+}
+
+// ViewKeyMap holds the key bindings of the file viewers. They may reuse keys
+// of the browser, since the two are never active together.
+type ViewKeyMap struct {
+	Search      key.Binding
+	SearchBack  key.Binding
+	NextMatch   key.Binding
+	PrevMatch   key.Binding
+	Goto        key.Binding
+	Wrap        key.Binding
+	ScrollLeft  key.Binding
+	ScrollRight key.Binding
+	LineNumbers key.Binding
+}
+
+var DefaultViewKeyMap = ViewKeyMap{
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search forward"),
+	),
+	SearchBack: key.NewBinding(
+		key.WithKeys("?"),
+		key.WithHelp("?", "search backward"),
+	),
+	NextMatch: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "find next match"),
+	),
+	PrevMatch: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "find previous match"),
+	),
+	Goto: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "go to a line, percentage or byte offset"),
+	),
+	Wrap: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "wrap long lines on or off"),
+	),
+	ScrollLeft: key.NewBinding(
+		key.WithKeys("shift+left", "<"),
+		key.WithHelp("shift+←/<", "scroll long lines left"),
+	),
+	ScrollRight: key.NewBinding(
+		key.WithKeys("shift+right", ">"),
+		key.WithHelp("shift+→/>", "scroll long lines right"),
+	),
+	LineNumbers: key.NewBinding(
+		key.WithKeys("l"),
+		key.WithHelp("l", "show line numbers on or off"),
+	),
+}
+
+// Bindings returns the bindings in the key map by action name.
+func (km *ViewKeyMap) Bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"Search":      &km.Search,
+		"SearchBack":  &km.SearchBack,
+		"NextMatch":   &km.NextMatch,
+		"PrevMatch":   &km.PrevMatch,
+		"Goto":        &km.Goto,
+		"Wrap":        &km.Wrap,
+		"ScrollLeft":  &km.ScrollLeft,
+		"ScrollRight": &km.ScrollRight,
+		"LineNumbers": &km.LineNumbers,
+	}
+}
+
+// keySymbols are the names shown in help text for some keys.
+var keySymbols = map[string]string{
+	"up":     "↑",
+	"down":   "↓",
+	"left":   "←",
+	"right":  "→",
+	"enter":  "↲",
+	"pgdown": "pgdn",
+}
+
+// Rebind replaces the keys of a binding and updates its help text to match.
+// Binding no keys disables the action.
+func Rebind(b *key.Binding, keys []string) {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k
+		for name, sym := range keySymbols {
+			if k == name || strings.HasSuffix(k, "+"+name) {
+				names[i] = strings.TrimSuffix(k, name) + sym
+				break
+			}
+		}
+	}
+	b.SetKeys(keys...)
+	b.SetHelp(strings.Join(names, "/"), b.Help().Desc)
+}
@@ -0,0 +1,100 @@
+package scroller
+
+import (
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// Wrapper is implemented by viewers whose long lines can either be wrapped
+// or scrolled sideways. While lines are wrapped, positions count visual
+// lines, so the cursor moves by visual line.
+type Wrapper interface {
+	// Wrapped reports whether long lines are wrapped.
+	Wrapped() bool
+
+	// SetWrap turns wrapping on or off.
+	SetWrap(on bool) error
+
+	// Pan scrolls sideways by delta columns when lines are not wrapped.
+	Pan(delta int)
+
+	// LineOf returns the line shown at position pos.
+	LineOf(pos, width int) int
+
+	// PosOf returns the first position that shows the line.
+	PosOf(line, width int) int
+}
+
+// LineNumberer is implemented by viewers that can show line numbers.
+type LineNumberer interface {
+	// LineNumbers reports whether line numbers are shown.
+	LineNumbers() bool
+
+	// SetLineNumbers shows or hides the line numbers.
+	SetLineNumbers(on bool)
+}
+
+// updateLayout handles the keys that change how lines are laid out:
+// wrapping, scrolling sideways and line numbers. It returns false if the
+// message is not about the layout.
+func (m *Model[T]) updateLayout(msg tea.Msg) (bool, tea.Cmd) {
+	km, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return false, nil
+	}
+	if n, ok := any(&m.Data).(LineNumberer); ok && key.Matches(km, DefaultViewKeyMap.LineNumbers) {
+		// The gutter takes width from the text, which may change how
+		// lines are wrapped
+		line := m.line()
+		n.SetLineNumbers(!n.LineNumbers())
+		m.SetCursor(m.pos(line))
+		return true, nil
+	}
+	w, ok := any(&m.Data).(Wrapper)
+	if !ok {
+		return false, nil
+	}
+	switch {
+	case key.Matches(km, DefaultViewKeyMap.Wrap):
+		line := m.line()
+		if err := w.SetWrap(!w.Wrapped()); err != nil {
+			m.Status = err.Error()
+			return true, nil
+		}
+		m.SetCursor(m.pos(line))
+		return true, nil
+
+	case key.Matches(km, DefaultViewKeyMap.ScrollLeft):
+		w.Pan(-max(m.width/2, 1))
+		return true, nil
+
+	case key.Matches(km, DefaultViewKeyMap.ScrollRight):
+		w.Pan(max(m.width/2, 1))
+		return true, nil
+	}
+	return false, nil
+}
+
+// resize sets the size of the view, keeping the cursor on the same line if
+// lines are wrapped.
+func (m *Model[T]) resize(width, height int) {
+	line := m.line()
+	m.width, m.height = width, height
+	m.cursor = m.pos(line)
+}
+
+// line returns the line at the cursor.
+func (m *Model[T]) line() int {
+	if w, ok := any(&m.Data).(Wrapper); ok && w.Wrapped() {
+		return w.LineOf(m.cursor, m.width)
+	}
+	return m.cursor
+}
+
+// pos returns the first position that shows the line.
+func (m *Model[T]) pos(line int) int {
+	if w, ok := any(&m.Data).(Wrapper); ok && w.Wrapped() {
+		return w.PosOf(line, m.width)
+	}
+	return line
+}
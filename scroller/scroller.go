@@ -3,23 +3,36 @@ package scroller
 import (
 	"strings"
 
+	"github.com/ancientlore/hermit2/nav"
+	"github.com/ancientlore/hermit2/theme"
 	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 )
 
 var (
-	header    = lipgloss.NewStyle().Background(lipgloss.Color("#888B7E"))
-	footer    = lipgloss.NewStyle().Background(lipgloss.Color("#888B7E"))
-	normal    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
-	highlight = lipgloss.NewStyle().Background(lipgloss.Color("#7D56F4"))
+	header    = theme.Default().Header
+	footer    = theme.Default().Footer
+	normal    = theme.Default().Text
+	highlight = theme.Default().Cursor
 )
 
+// SetTheme sets the styles of the header, footer, lines and cursor.
+func SetTheme(t theme.Theme) {
+	header = t.Header
+	footer = t.Footer
+	normal = t.Text
+	highlight = t.Cursor
+}
+
 // Model implements scrolling behavior over a Viewer.
 type Model[T Viewer] struct {
 	Header         string         // Header text
+	Status         string         // Message shown instead of the viewer's footer
+	Blur           bool           // Whether the cursor is hidden, as when another pane has the focus
 	Data           T              // The view we are using
-	Prev           tea.Model      // The previous model (for going back)
+	query          Query          // The last search
+	search         *search        // The running search, if any
 	cursor         int            // Current position of cursor
 	offset         int            // The offset of the view (enables scrolling)
 	width          int            // The width of the current view
@@ -36,6 +49,19 @@ func (m Model[T]) Init() tea.Cmd {
 
 // Update handles messages in order to implement scrolling.
 func (m Model[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyPressMsg); ok {
+		m.Status = ""
+	}
+	if ok, cmd := m.updateSearch(msg); ok {
+		return m, cmd
+	}
+	if ok, cmd := m.updateGoto(msg); ok {
+		return m, cmd
+	}
+	if ok, cmd := m.updateLayout(msg); ok {
+		return m, cmd
+	}
+
 	switch msg := msg.(type) {
 
 	// Is it a key press?
@@ -55,10 +81,7 @@ func (m Model[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.fixOffset()
 
 		case key.Matches(msg, DefaultKeyMap.Left):
-			if m.Prev != nil {
-				m.Data.Close()
-				return m.Prev, func() tea.Msg { return tea.WindowSizeMsg{Width: m.width, Height: m.height + 2} }
-			}
+			return m, nav.Pop
 
 		case key.Matches(msg, DefaultKeyMap.Home):
 			m.cursor = 0
@@ -77,15 +100,13 @@ func (m Model[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.fixOffset()
 
 		case key.Matches(msg, DefaultKeyMap.Quit):
-			m.Data.Close()
-			return m, tea.Quit
+			return m, nav.Quit
 		}
 
 	case tea.WindowSizeMsg:
-		m.width = msg.Width
-		m.height = msg.Height - 2 // account for header and footer
-		m.normalStyle = normal.Width(m.width).Height(1).MaxWidth(m.width)
-		m.highlightStyle = highlight.Width(m.width).Height(1).MaxWidth(m.width)
+		m.resize(msg.Width, msg.Height-2) // account for header and footer
+		m.normalStyle = normal.Width(m.width).Height(1).MaxWidth(m.width).MaxHeight(1)
+		m.highlightStyle = highlight.Width(m.width).Height(1).MaxWidth(m.width).MaxHeight(1)
 		m.fixOffset()
 	}
 
@@ -98,7 +119,7 @@ func (m Model[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // by the class embedding the scroller.
 func (m Model[T]) View() tea.View {
 	// Header
-	s := header.Width(m.width).Height(1).Render(m.Header) + "\n"
+	s := header.Width(m.width).Height(1).MaxHeight(1).Render(m.Header) + "\n"
 
 	// Viewport
 	lines := 0
@@ -107,7 +128,9 @@ func (m Model[T]) View() tea.View {
 	for i := m.offset; i < m.Data.Len(m.width) && i < m.height+m.offset; i++ {
 		style := m.normalStyle
 		if m.cursor == i {
-			style = m.highlightStyle
+			if !m.Blur {
+				style = m.highlightStyle
+			}
 			cursorStart = lines
 		}
 		line := m.Data.Render(i, m.width, style)
@@ -123,7 +146,7 @@ func (m Model[T]) View() tea.View {
 	repeat := m.height - lines
 	if repeat > 0 {
 		// Too few lines
-		s += strings.Repeat("\n", repeat)
+		s += strings.Repeat(m.normalStyle.Render("")+"\n", repeat)
 	} else if repeat < 0 {
 		// Too many lines (due to text wrap)
 		// a[0] is the header
@@ -144,17 +167,36 @@ func (m Model[T]) View() tea.View {
 	}
 
 	// Footer
-	s += m.Data.Footer(m.cursor, m.width, footer.Width(m.width).Height(1))
+	if m.Status != "" {
+		s += footer.Width(m.width).Height(1).MaxWidth(m.width).MaxHeight(1).Render(m.Status)
+	} else {
+		s += m.Data.Footer(m.cursor, m.width, footer.Width(m.width).Height(1).MaxWidth(m.width).MaxHeight(1))
+	}
 	v := tea.NewView(s)
 	v.AltScreen = true
 	return v
 }
 
+// Close stops any search and closes the viewer. It is called when the
+// model leaves the screen.
+func (m Model[T]) Close() error {
+	if m.search != nil {
+		m.search.cancel()
+	}
+	return m.Data.Close()
+}
+
 // Cursor returns the position of the cursor.
 func (m Model[T]) Cursor() int {
 	return m.cursor
 }
 
+// SetCursor moves the cursor to position i.
+func (m *Model[T]) SetCursor(i int) {
+	m.cursor = i
+	m.fixOffset()
+}
+
 // MoveCursor moves the cursor by delta.
 func (m *Model[T]) MoveCursor(delta int) {
 	m.cursor += delta
@@ -166,7 +208,7 @@ func (m Model[T]) Width() int {
 	return m.width
 }
 
-// Height returns the height of the view.
+// Height returns the height of the view, including the header and footer.
 func (m Model[T]) Height() int {
 	return m.height + 2
 }
@@ -0,0 +1,175 @@
+package scroller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/ancientlore/hermit2/dialog"
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// SearchMode says how a search pattern is matched.
+type SearchMode int
+
+const (
+	Literal    SearchMode = iota // Match the text exactly
+	IgnoreCase                   // Match the text without regard to case
+	Regexp                       // Match a regular expression
+)
+
+var searchModes = []string{"literal", "ignore case", "regexp"}
+
+func (mode SearchMode) String() string {
+	if mode >= 0 && int(mode) < len(searchModes) {
+		return searchModes[mode]
+	}
+	return fmt.Sprintf("SearchMode(%d)", int(mode))
+}
+
+// Query is something to search for.
+type Query struct {
+	Pattern string
+	Mode    SearchMode
+}
+
+// Regexp compiles the query into a regular expression.
+func (q Query) Regexp() (*regexp.Regexp, error) {
+	switch q.Mode {
+	case IgnoreCase:
+		return regexp.Compile("(?i)" + regexp.QuoteMeta(q.Pattern))
+	case Regexp:
+		return regexp.Compile(q.Pattern)
+	}
+	return regexp.Compile(regexp.QuoteMeta(q.Pattern))
+}
+
+// Searcher is implemented by viewers that can be searched. Search runs on
+// another goroutine while the viewer is being rendered, so it must not
+// change the viewer.
+type Searcher interface {
+	// Search returns the position of the next match after from, or the
+	// previous one before it, or -1 if there is none.
+	Search(ctx context.Context, q Query, from, width int, backward bool) (int, error)
+
+	// SetHighlight sets the query whose matches are highlighted by Render.
+	// An empty pattern turns highlighting off.
+	SetHighlight(q Query)
+}
+
+// search is a search that is running.
+type search struct {
+	cancel context.CancelFunc
+}
+
+// searchID asks for a pattern to search for.
+type searchID struct {
+	backward bool
+}
+
+// searchDoneMsg reports the result of a search. It goes to every screen,
+// since another may have been opened over the one searched.
+type searchDoneMsg struct {
+	s   *search
+	q   Query
+	pos int
+	err error
+}
+
+// Broadcast marks searchDoneMsg as a message for every screen.
+func (searchDoneMsg) Broadcast() {}
+
+// lastMode is the search mode used last, which is offered next time.
+var lastMode SearchMode
+
+// updateSearch handles the search keys and messages. It returns false if
+// the message is not about searching.
+func (m *Model[T]) updateSearch(msg tea.Msg) (bool, tea.Cmd) {
+	s, ok := any(&m.Data).(Searcher)
+	if !ok {
+		return false, nil
+	}
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, DefaultViewKeyMap.Search, DefaultViewKeyMap.SearchBack):
+			backward := key.Matches(msg, DefaultViewKeyMap.SearchBack)
+			title := "Search forward:"
+			if backward {
+				title = "Search backward:"
+			}
+			d := dialog.NewInput(searchID{backward: backward}, title, m.query.Pattern).WithOptions(searchModes, int(lastMode))
+			return true, dialog.Open(d)
+
+		case key.Matches(msg, DefaultViewKeyMap.NextMatch, DefaultViewKeyMap.PrevMatch):
+			if m.query.Pattern == "" {
+				return true, nil
+			}
+			return true, m.startSearch(m.query, key.Matches(msg, DefaultViewKeyMap.PrevMatch))
+		}
+
+	case dialog.InputMsg:
+		if id, ok := msg.ID.(searchID); ok {
+			if msg.Value == "" {
+				m.query = Query{}
+				s.SetHighlight(m.query)
+				return true, nil
+			}
+			lastMode = SearchMode(msg.Option)
+			m.query = Query{Pattern: msg.Value, Mode: lastMode}
+			if _, err := m.query.Regexp(); err != nil {
+				m.Status = err.Error()
+				return true, nil
+			}
+			s.SetHighlight(m.query)
+			return true, m.startSearch(m.query, id.backward)
+		}
+
+	case searchDoneMsg:
+		if msg.s != m.search {
+			// A search for another model, or one that was replaced
+			return false, nil
+		}
+		m.search = nil
+		switch {
+		case msg.err != nil:
+			m.Status = msg.err.Error()
+		case msg.pos < 0:
+			m.Status = fmt.Sprintf("No more matches for %q", msg.q.Pattern)
+		default:
+			m.Status = ""
+			m.SetCursor(msg.pos)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// startSearch cancels any running search and starts a new one from the
+// cursor in the background.
+func (m *Model[T]) startSearch(q Query, backward bool) tea.Cmd {
+	m.stopSearch()
+	data := m.Data
+	s := any(&data).(Searcher)
+	ctx, cancel := context.WithCancel(context.Background())
+	srch := &search{cancel: cancel}
+	m.search = srch
+	m.Status = fmt.Sprintf("Searching for %q…", q.Pattern)
+	from, width := m.cursor, m.width
+	return func() tea.Msg {
+		pos, err := s.Search(ctx, q, from, width, backward)
+		if ctx.Err() != nil {
+			err = nil
+		}
+		return searchDoneMsg{s: srch, q: q, pos: pos, err: err}
+	}
+}
+
+// stopSearch cancels the running search, if any.
+func (m *Model[T]) stopSearch() {
+	if m.search != nil {
+		m.search.cancel()
+		m.search = nil
+	}
+}
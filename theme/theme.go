@@ -0,0 +1,134 @@
+// Package theme defines the colors used to draw hermit's screens. A few
+// themes are built in; the configuration file picks one and may override
+// its colors.
+package theme
+
+import (
+	"os"
+	"sort"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Theme holds the styles of the parts of the screen.
+type Theme struct {
+	Header          lipgloss.Style // Title line at the top
+	Footer          lipgloss.Style // Status line at the bottom
+	Text            lipgloss.Style // Normal lines
+	Cursor          lipgloss.Style // Line at the cursor
+	Selected        lipgloss.Style // Names of selected entries
+	Directory       lipgloss.Style // Folder names
+	Hidden          lipgloss.Style // Dot file names
+	HiddenDirectory lipgloss.Style // Dot folder names
+	Symlink         lipgloss.Style // Names of symbolic links
+	Executable      lipgloss.Style // Names of executable files
+	Error           lipgloss.Style // Errors
+}
+
+// DefaultName is the name of the theme used unless another is chosen.
+const DefaultName = "default"
+
+// builtin holds the themes that are built in, by name.
+var builtin = map[string]Theme{
+	DefaultName: {
+		Header:          lipgloss.NewStyle().Background(lipgloss.Color("#888B7E")),
+		Footer:          lipgloss.NewStyle().Background(lipgloss.Color("#888B7E")),
+		Text:            lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")),
+		Cursor:          lipgloss.NewStyle().Background(lipgloss.Color("#7D56F4")),
+		Selected:        lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF55")).Bold(true),
+		Directory:       lipgloss.NewStyle().Foreground(lipgloss.Color("#AA00AA")),
+		Hidden:          lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA")),
+		HiddenDirectory: lipgloss.NewStyle().Foreground(lipgloss.Color("#770077")),
+		Symlink:         lipgloss.NewStyle().Foreground(lipgloss.Color("#00AAAA")),
+		Executable:      lipgloss.NewStyle().Foreground(lipgloss.Color("#55FF55")),
+		Error:           lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")),
+	},
+
+	// The blue and cyan look of Norton Commander
+	"norton": {
+		Header:          lipgloss.NewStyle().Background(lipgloss.Color("#00AAAA")).Foreground(lipgloss.Color("#000000")),
+		Footer:          lipgloss.NewStyle().Background(lipgloss.Color("#00AAAA")).Foreground(lipgloss.Color("#000000")),
+		Text:            lipgloss.NewStyle().Background(lipgloss.Color("#0000AA")).Foreground(lipgloss.Color("#55FFFF")),
+		Cursor:          lipgloss.NewStyle().Background(lipgloss.Color("#00AAAA")).Foreground(lipgloss.Color("#000000")),
+		Selected:        lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF55")).Bold(true),
+		Directory:       lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true),
+		Hidden:          lipgloss.NewStyle().Foreground(lipgloss.Color("#5555FF")),
+		HiddenDirectory: lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA")).Bold(true),
+		Symlink:         lipgloss.NewStyle().Foreground(lipgloss.Color("#FF55FF")),
+		Executable:      lipgloss.NewStyle().Foreground(lipgloss.Color("#55FF55")),
+		Error:           lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Bold(true),
+	},
+
+	// Bright colors on black for readability
+	"high-contrast": {
+		Header:          lipgloss.NewStyle().Background(lipgloss.Color("#FFFFFF")).Foreground(lipgloss.Color("#000000")).Bold(true),
+		Footer:          lipgloss.NewStyle().Background(lipgloss.Color("#FFFFFF")).Foreground(lipgloss.Color("#000000")).Bold(true),
+		Text:            lipgloss.NewStyle().Background(lipgloss.Color("#000000")).Foreground(lipgloss.Color("#FFFFFF")),
+		Cursor:          lipgloss.NewStyle().Background(lipgloss.Color("#FFFF00")).Foreground(lipgloss.Color("#000000")),
+		Selected:        lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true).Underline(true),
+		Directory:       lipgloss.NewStyle().Foreground(lipgloss.Color("#00FFFF")).Bold(true),
+		Hidden:          lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0")),
+		HiddenDirectory: lipgloss.NewStyle().Foreground(lipgloss.Color("#00C0C0")).Bold(true),
+		Symlink:         lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF")).Bold(true),
+		Executable:      lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00")).Bold(true),
+		Error:           lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true),
+	},
+
+	// Text attributes only, for terminals without color
+	"mono": Mono,
+}
+
+// Mono is the theme used when colors are turned off with NO_COLOR.
+var Mono = Theme{
+	Header:          lipgloss.NewStyle().Reverse(true),
+	Footer:          lipgloss.NewStyle().Reverse(true),
+	Text:            lipgloss.NewStyle(),
+	Cursor:          lipgloss.NewStyle().Reverse(true),
+	Selected:        lipgloss.NewStyle().Bold(true).Underline(true),
+	Directory:       lipgloss.NewStyle().Bold(true),
+	Hidden:          lipgloss.NewStyle().Faint(true),
+	HiddenDirectory: lipgloss.NewStyle().Bold(true).Faint(true),
+	Symlink:         lipgloss.NewStyle().Italic(true),
+	Executable:      lipgloss.NewStyle().Underline(true),
+	Error:           lipgloss.NewStyle().Bold(true),
+}
+
+// Default returns the default theme.
+func Default() Theme {
+	return builtin[DefaultName]
+}
+
+// Get returns the built-in theme with the given name.
+func Get(name string) (Theme, bool) {
+	t, ok := builtin[name]
+	return t, ok
+}
+
+// Names returns the names of the built-in themes in order.
+func Names() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NoColor reports whether colors are turned off with the NO_COLOR
+// environment variable (see https://no-color.org).
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// Row returns s with the background and reverse setting of the row it is
+// drawn in, so that a row's colors carry across the parts of it that have
+// their own styles.
+func Row(s, row lipgloss.Style) lipgloss.Style {
+	s = s.Reverse(row.GetReverse())
+	if bg := row.GetBackground(); bg != nil {
+		if _, none := bg.(lipgloss.NoColor); !none {
+			s = s.Background(bg)
+		}
+	}
+	return s
+}
@@ -3,30 +3,58 @@ package views
 import (
 	"fmt"
 	"io"
+	"io/fs"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/ancientlore/hermit2/scroller"
 	"charm.land/lipgloss/v2"
 )
 
-// Binary manages the base logic of rendering binary data.
+// Binary manages the base logic of rendering binary data. Only the rows
+// being rendered are read, through a small cache of blocks, so files of any
+// size can be viewed. The size of the file is checked again from time to
+// time, so files that grow while they are open can be followed.
 type Binary struct {
-	rdr  io.Closer
-	data []byte
+	*binaryFile
+	match []byte // Search pattern to highlight, if any
+	fold  bool   // Whether the pattern ignores case
+}
+
+const (
+	blockSize     = 4096                   // Bytes in a cached block
+	maxBinBlocks  = 64                     // Blocks kept in the cache
+	sizeCheckTime = 500 * time.Millisecond // How often the file size is checked
+)
+
+// binaryFile reads blocks of a file, keeping the most recent ones.
+// It may be read by a search while it is being rendered, so mu guards the
+// cache and size but is not held while reading.
+type binaryFile struct {
+	mu      sync.Mutex
+	seekMu  sync.Mutex // Held while the reader is moved and read from
+	rdr     io.ReadSeekCloser
+	blocks  map[int64][]byte // Cached full blocks by block number
+	size    int64            // Size of the file when last checked
+	checked time.Time        // When the size was last checked
 }
 
 // Render formats the line at position i using the base style and view width.
 func (v Binary) Render(i, width int, baseStyle lipgloss.Style) string {
 	w := dataWidth(width)
-	offset := i * w
-	if offset >= len(v.data) {
+	offset := int64(i) * int64(w)
+	if offset >= v.Size() {
 		return ""
 	}
-	end := offset + w
-	if end > len(v.data) {
-		end = len(v.data)
+	chunk := v.read(offset, w)
+	if v.match != nil {
+		if marks := v.marks(offset, len(chunk)); marks != nil {
+			return baseStyle.Render(renderMarked(chunk, marks, w))
+		}
 	}
-	chunk := v.data[offset:end]
 
 	s := fmt.Sprintf("% X%s  ", chunk, strings.Repeat("   ", w-len(chunk)))
 	var x strings.Builder
@@ -42,41 +70,153 @@ func (v Binary) Render(i, width int, baseStyle lipgloss.Style) string {
 
 // Footer formats the footer using the base style and view width.
 func (v Binary) Footer(cursor, width int, baseStyle lipgloss.Style) string {
-	return baseStyle.Render(fmt.Sprintf("%d / %d bytes (%d bytes per row)", cursor*dataWidth(width), len(v.data), dataWidth(width)))
+	return baseStyle.Render(fmt.Sprintf("%d / %d bytes (%d bytes per row)", cursor*dataWidth(width), v.Size(), dataWidth(width)))
+}
+
+// Targets describes the positions Locate accepts.
+func (v Binary) Targets() string {
+	return "byte offset (decimal or 0x hex) or percentage"
+}
+
+// Locate returns the row holding a byte offset, given in decimal or as hex
+// after 0x, or a percentage of the way through the file.
+func (v Binary) Locate(target string, width int) (int, error) {
+	size := v.Size()
+	var offset int64
+	p, ok, err := scroller.ParsePercent(target)
+	switch {
+	case err != nil:
+		return 0, err
+	case ok:
+		offset = min(int64(p/100*float64(size)), max(size-1, 0))
+	default:
+		offset, err = strconv.ParseInt(strings.TrimSpace(target), 0, 64)
+		if err != nil || offset < 0 {
+			return 0, fmt.Errorf("invalid offset %q", target)
+		}
+		if offset >= size {
+			return 0, fmt.Errorf("offset %d is past the end of the file (%d bytes)", offset, size)
+		}
+	}
+	return int(offset / int64(dataWidth(width))), nil
 }
 
 // Len returns the number of lines of text.
 func (v Binary) Len(width int) int {
-	w := dataWidth(width)
-	l := len(v.data) / w
-	if len(v.data)%w > 0 {
+	w := int64(dataWidth(width))
+	size := v.Size()
+	l := size / w
+	if size%w > 0 {
 		l++
 	}
-	return l
+	return int(l)
 }
 
 // Close closes the underlying reader.
 func (v Binary) Close() error {
-	if v.rdr != nil {
+	if v.binaryFile != nil && v.rdr != nil {
 		return v.rdr.Close()
 	}
 	return nil
 }
 
-// NewBinary prepares binary data for rendering by reading it all into memory.
+// Size returns the size of the file, checking it again if it has not
+// been checked for a while.
+func (f *binaryFile) Size() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if time.Since(f.checked) < sizeCheckTime {
+		return f.size
+	}
+	f.checked = time.Now()
+	var size int64
+	if st, ok := f.rdr.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		info, err := st.Stat()
+		if err != nil {
+			return f.size
+		}
+		size = info.Size()
+	} else {
+		f.seekMu.Lock()
+		n, err := f.rdr.Seek(0, io.SeekEnd)
+		f.seekMu.Unlock()
+		if err != nil {
+			return f.size
+		}
+		size = n
+	}
+	if size < f.size {
+		// The file was truncated, so the cached blocks may be stale
+		clear(f.blocks)
+	}
+	f.size = size
+	return size
+}
+
+// read returns up to n bytes of the file starting at offset.
+func (f *binaryFile) read(offset int64, n int) []byte {
+	var b []byte
+	for len(b) < n {
+		blk := f.block(offset / blockSize)
+		pos := int(offset % blockSize)
+		if pos >= len(blk) {
+			break
+		}
+		c := blk[pos:min(len(blk), pos+n-len(b))]
+		b = append(b, c...)
+		offset += int64(len(c))
+	}
+	return b
+}
+
+// block returns block number k. Only full blocks are cached, since the
+// last block may still grow.
+func (f *binaryFile) block(k int64) []byte {
+	f.mu.Lock()
+	blk, ok := f.blocks[k]
+	f.mu.Unlock()
+	if ok {
+		return blk
+	}
+	blk = make([]byte, blockSize)
+	n, err := f.readAt(blk, k*blockSize)
+	blk = blk[:n]
+	if err == nil && n == blockSize {
+		f.mu.Lock()
+		if len(f.blocks) >= maxBinBlocks {
+			clear(f.blocks)
+		}
+		f.blocks[k] = blk
+		f.mu.Unlock()
+	}
+	return blk
+}
+
+// readAt reads len(b) bytes at offset off, or fewer at the end of the file.
+// Readers that can't read at an offset are read one caller at a time.
+func (f *binaryFile) readAt(b []byte, off int64) (int, error) {
+	if ra, ok := f.rdr.(io.ReaderAt); ok {
+		return ra.ReadAt(b, off)
+	}
+	f.seekMu.Lock()
+	defer f.seekMu.Unlock()
+	if _, err := f.rdr.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(f.rdr, b)
+}
+
+// NewBinary prepares a file for rendering as binary data.
 func NewBinary(rdr io.ReadSeekCloser) (*Binary, error) {
-	_, err := rdr.Seek(0, io.SeekStart)
-	if err != nil {
-		return nil, err
+	f := &binaryFile{
+		rdr:    rdr,
+		blocks: make(map[int64][]byte),
 	}
-	data, err := io.ReadAll(rdr)
-	if err != nil {
+	if _, err := rdr.Seek(0, io.SeekStart); err != nil {
 		return nil, err
 	}
-	return &Binary{
-		rdr:  rdr,
-		data: data,
-	}, nil
+	f.Size()
+	return &Binary{binaryFile: f}, nil
 }
 
 func dataWidth(width int) int {
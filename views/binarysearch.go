@@ -0,0 +1,181 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ancientlore/hermit2/scroller"
+)
+
+// searchChunk is the number of bytes searched at a time.
+const searchChunk = 1 << 20
+
+// binaryPattern converts a query into the bytes to search for. Hex bytes are
+// given as pairs of hex digits, either after 0x or separated by spaces, as in
+// "0x4D5A" or "4D 5A 90"; anything else is searched for as text.
+func binaryPattern(q scroller.Query) ([]byte, error) {
+	if q.Mode == scroller.Regexp {
+		return nil, errors.New("regular expressions can't be used to search binary data")
+	}
+	if h, ok := strings.CutPrefix(q.Pattern, "0x"); ok {
+		b, err := hex.DecodeString(strings.ReplaceAll(h, " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex pattern: %w", err)
+		}
+		return b, nil
+	}
+	if fields := strings.Fields(q.Pattern); len(fields) > 1 {
+		if b, err := hex.DecodeString(strings.Join(fields, "")); err == nil && isHexPairs(fields) {
+			return b, nil
+		}
+	}
+	return []byte(q.Pattern), nil
+}
+
+// isHexPairs reports whether each field is two hex digits.
+func isHexPairs(fields []string) bool {
+	for _, f := range fields {
+		if len(f) != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// foldASCII returns a copy of b with ASCII letters in lower case.
+func foldASCII(b []byte) []byte {
+	f := make([]byte, len(b))
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		f[i] = c
+	}
+	return f
+}
+
+// Search returns the row of the next match after row from, or of the
+// previous one before it, or -1 if there is none.
+func (v Binary) Search(ctx context.Context, q scroller.Query, from, width int, backward bool) (int, error) {
+	pattern, err := binaryPattern(q)
+	if err != nil || len(pattern) == 0 {
+		return -1, err
+	}
+	fold := q.Mode == scroller.IgnoreCase
+	if fold {
+		pattern = foldASCII(pattern)
+	}
+	w := int64(dataWidth(width))
+	size := v.Size()
+	buf := make([]byte, searchChunk+len(pattern)-1)
+	find := func(lo, hi int64, last bool) (int64, error) {
+		n, err := v.readAt(buf[:hi-lo], lo)
+		if n == 0 && err != nil {
+			return -1, err
+		}
+		b := buf[:n]
+		if fold {
+			b = foldASCII(b)
+		}
+		i := bytes.Index(b, pattern)
+		if last {
+			i = bytes.LastIndex(b, pattern)
+		}
+		if i < 0 {
+			return -1, nil
+		}
+		return lo + int64(i), nil
+	}
+
+	if !backward {
+		for pos := (int64(from) + 1) * w; pos < size; pos += searchChunk {
+			if ctx.Err() != nil {
+				return -1, ctx.Err()
+			}
+			off, err := find(pos, min(pos+int64(len(buf)), size), false)
+			if err != nil || off >= 0 {
+				return int(off / w), err
+			}
+		}
+		return -1, nil
+	}
+
+	for end := min(int64(from)*w, size); end > 0; end -= searchChunk {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		lo := max(end-searchChunk, 0)
+		off, err := find(lo, min(end+int64(len(pattern))-1, size), true)
+		if err != nil || off >= 0 {
+			return int(off / w), err
+		}
+	}
+	return -1, nil
+}
+
+// SetHighlight sets the query whose matches are highlighted.
+func (v *Binary) SetHighlight(q scroller.Query) {
+	v.match, v.fold = nil, false
+	if q.Pattern == "" {
+		return
+	}
+	if p, err := binaryPattern(q); err == nil && len(p) > 0 {
+		v.fold = q.Mode == scroller.IgnoreCase
+		v.match = p
+		if v.fold {
+			v.match = foldASCII(p)
+		}
+	}
+}
+
+// marks returns which of the n bytes at offset are part of a match,
+// or nil if none are.
+func (v Binary) marks(offset int64, n int) []bool {
+	p := len(v.match)
+	lo := max(offset-int64(p-1), 0)
+	b := v.read(lo, int(offset-lo)+n+p-1)
+	if v.fold {
+		b = foldASCII(b)
+	}
+	var marks []bool
+	for i := 0; i+p <= len(b); i++ {
+		if !bytes.Equal(b[i:i+p], v.match) {
+			continue
+		}
+		if marks == nil {
+			marks = make([]bool, n)
+		}
+		for j := i; j < i+p; j++ {
+			if k := int(lo-offset) + j; k >= 0 && k < n {
+				marks[k] = true
+			}
+		}
+	}
+	return marks
+}
+
+// renderMarked formats a row of w bytes, highlighting the marked ones.
+func renderMarked(chunk []byte, marks []bool, w int) string {
+	var h, x strings.Builder
+	for i, c := range chunk {
+		hs := fmt.Sprintf("%02X", c)
+		xs := "."
+		if unicode.IsPrint(rune(c)) {
+			xs = string(rune(c))
+		}
+		if marks[i] {
+			hs, xs = found.Render(hs), found.Render(xs)
+		}
+		if i > 0 {
+			h.WriteByte(' ')
+		}
+		h.WriteString(hs)
+		x.WriteString(xs)
+	}
+	return h.String() + strings.Repeat("   ", w-len(chunk)) + "  " + x.String()
+}
@@ -0,0 +1,45 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/ancientlore/hermit2/config"
+	"charm.land/lipgloss/v2"
+)
+
+// Bookmarks is a viewer for the bookmark slots, including empty ones.
+type Bookmarks struct {
+	b config.Bookmarks
+}
+
+// NewBookmarks creates a viewer for the given bookmarks.
+func NewBookmarks(b config.Bookmarks) Bookmarks {
+	return Bookmarks{b: b}
+}
+
+// Render formats the line at position i using the base style and view width.
+func (v Bookmarks) Render(i, width int, baseStyle lipgloss.Style) string {
+	if i < 0 || i >= len(v.b) {
+		return baseStyle.Render("")
+	}
+	bm := v.b[i]
+	if bm.Path == "" {
+		return baseStyle.Render(fmt.Sprintf("  %d  -", i))
+	}
+	return baseStyle.Render(fmt.Sprintf("  %d  %-24s  %s", i, bm.Name, bm.Path))
+}
+
+// Footer formats the footer using the base style and view width.
+func (v Bookmarks) Footer(cursor, width int, baseStyle lipgloss.Style) string {
+	return baseStyle.Render("Press a slot number, or move to a bookmark and press →/↲")
+}
+
+// Len returns the number of slots.
+func (v Bookmarks) Len(width int) int {
+	return len(v.b)
+}
+
+// Close closes the viewer, if necessary.
+func (v Bookmarks) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package views
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ancientlore/hermit2/config"
+	"charm.land/lipgloss/v2"
+)
+
+// Commands is a viewer for the user-defined commands.
+type Commands struct {
+	keys []string                  // Command keys in sorted order
+	cmds map[string]config.Command // Commands by key
+}
+
+// NewCommands creates a viewer for the given commands.
+func NewCommands(cmds map[string]config.Command) Commands {
+	keys := make([]string, 0, len(cmds))
+	for k := range cmds {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return Commands{keys: keys, cmds: cmds}
+}
+
+// At returns the key and command at position i.
+func (v Commands) At(i int) (string, config.Command, bool) {
+	if i >= 0 && i < len(v.keys) {
+		return v.keys[i], v.cmds[v.keys[i]], true
+	}
+	return "", config.Command{}, false
+}
+
+// Lookup returns the command bound to key k.
+func (v Commands) Lookup(k string) (config.Command, bool) {
+	cmd, ok := v.cmds[k]
+	return cmd, ok
+}
+
+// Render formats the line at position i using the base style and view width.
+func (v Commands) Render(i, width int, baseStyle lipgloss.Style) string {
+	k, cmd, ok := v.At(i)
+	if !ok {
+		return baseStyle.Render("")
+	}
+	return baseStyle.Render(fmt.Sprintf("  %s  %-32s  %s", k, cmd.Description, cmd.Macro))
+}
+
+// Footer formats the footer using the base style and view width.
+func (v Commands) Footer(cursor, width int, baseStyle lipgloss.Style) string {
+	if len(v.keys) == 0 {
+		return baseStyle.Render("No commands are configured")
+	}
+	return baseStyle.Render("Press a command key, or move to a command and press →/↲")
+}
+
+// Len returns the number of commands.
+func (v Commands) Len(width int) int {
+	return len(v.keys)
+}
+
+// Close closes the viewer, if necessary.
+func (v Commands) Close() error {
+	return nil
+}
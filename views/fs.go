@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/ancientlore/hermit2/theme"
 	"charm.land/lipgloss/v2"
 )
 
@@ -16,21 +17,38 @@ const (
 	timeFormatNew = "Mon Jan _2 15:04"
 )
 
+// compactWidth is the width below which rows leave out the mode and time,
+// as in one of two panes, so that names can still be seen.
+const compactWidth = 60
+
+// colors holds the styles of file names.
+var colors = theme.Default()
+
+// Settings applied when a folder is read.
 var (
-	normal      = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
-	bold        = lipgloss.NewStyle().Foreground(lipgloss.Color("#AA00AA"))
-	special     = lipgloss.NewStyle().Foreground(lipgloss.Color("#AAAAAA"))
-	specialbold = lipgloss.NewStyle().Foreground(lipgloss.Color("#770077"))
+	DefaultSort    = SortByExt // Sort order for new listings
+	DefaultReverse = false     // Whether new listings are sorted in reverse
+	ShowHidden     = true      // Whether dot files are listed
 )
 
+// SetTheme sets the styles used for file names.
+func SetTheme(t theme.Theme) {
+	colors = t
+}
+
 // FS is a viewer for a fs.FS.
 type FS struct {
-	fsys     fs.FS         // The filesystem being browsed
-	root     string        // The name for the root of the file system
-	folder   string        // The current folder in the file system
-	entries  []fs.DirEntry // The list of directory entries read
-	infos    []fs.FileInfo // Pre-cached file info for sorting and rendering
-	selected []bool        // Whether an entry is selected
+	fsys     fs.FS           // The filesystem being browsed
+	root     string          // The name for the root of the file system
+	folder   string          // The current folder in the file system
+	entries  []fs.DirEntry   // The list of directory entries read
+	infos    []fs.FileInfo   // Pre-cached file info for sorting and rendering
+	selected []bool          // Whether an entry is selected
+	order    SortOrder       // The sort order of the entries
+	reverse  bool            // Whether the sort order is reversed
+	filter   string          // Only entries matching the filter are shown
+	shown    []int           // Positions of the entries shown when filtering
+	links    map[string]link // Where symbolic links lead, by name
 }
 
 // Title returns the full name of the current folder.
@@ -55,36 +73,72 @@ func (fsv FS) FS() fs.FS {
 
 // At returns the directory entry at position i.
 func (fsv FS) At(i int) fs.DirEntry {
-	if i >= 0 && i < len(fsv.entries) {
-		return fsv.entries[i]
+	if p := fsv.pos(i); p >= 0 {
+		return fsv.entries[p]
 	}
 	return nil
 }
 
+// Index returns the position of the entry with the given name, or -1
+// if there is no such entry or it is filtered out.
+func (fsv FS) Index(name string) int {
+	for i := 0; i < fsv.Len(0); i++ {
+		if fsv.entries[fsv.pos(i)].Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // Selected returns whether the entry at position i is selected.
 func (fsv FS) Selected(i int) bool {
-	if i >= 0 && i < len(fsv.selected) {
-		return fsv.selected[i]
+	if p := fsv.pos(i); p >= 0 {
+		return fsv.selected[p]
 	}
 	return false
 }
 
 // Select sets the selected flag at position i to b.
 func (fsv *FS) Select(i int, b bool) {
-	if i >= 0 && i < len(fsv.selected) {
-		fsv.selected[i] = b
+	if p := fsv.pos(i); p >= 0 {
+		fsv.selected[p] = b
 	}
 }
 
 // ToggleSelect toggles the selected flag at position i.
 func (fsv *FS) ToggleSelect(i int) {
-	if i >= 0 && i < len(fsv.selected) {
-		fsv.selected[i] = !fsv.selected[i]
+	if p := fsv.pos(i); p >= 0 {
+		fsv.selected[p] = !fsv.selected[p]
+	}
+}
+
+// SelectedEntries returns all selected entries, including any that
+// are filtered out.
+func (fsv FS) SelectedEntries() []fs.DirEntry {
+	var a []fs.DirEntry
+	for i, entry := range fsv.entries {
+		if fsv.selected[i] {
+			a = append(a, entry)
+		}
 	}
+	return a
 }
 
-// Len returns the number of file entries.
+// SelectNames selects the entries with the given names, including any
+// that are filtered out.
+func (fsv *FS) SelectNames(names []string) {
+	for i, entry := range fsv.entries {
+		if slices.Contains(names, entry.Name()) {
+			fsv.selected[i] = true
+		}
+	}
+}
+
+// Len returns the number of file entries shown.
 func (fsv FS) Len(width int) int {
+	if fsv.filter != "" {
+		return len(fsv.shown)
+	}
 	return len(fsv.entries)
 }
 
@@ -96,6 +150,10 @@ func (fsv FS) Close() error {
 // Render formats the line at position i using the base style and view width.
 func (fsv FS) Render(i, width int, baseStyle lipgloss.Style) string {
 	var s string
+	i = fsv.pos(i)
+	if i < 0 {
+		return baseStyle.Render("")
+	}
 	choice := fsv.entries[i]
 	// Is this choice selected?
 	checked := " " // not selected
@@ -105,38 +163,61 @@ func (fsv FS) Render(i, width int, baseStyle lipgloss.Style) string {
 
 	// Render the row
 	info := fsv.infos[i]
-	if info != nil {
+	ns := theme.Row(fsv.nameStyle(i), baseStyle)
+	if info != nil && width < compactWidth {
+		s = baseStyle.Render(fmt.Sprintf("%s %10d %s%s", checked, info.Size(), fsv.renderName(choice.Name(), ns), fsv.renderLink(choice.Name(), baseStyle)))
+	} else if info != nil {
 		n := time.Now().Local()
 		t := info.ModTime().Local()
 		format := timeFormatNew
 		if t.Year() < n.Year() {
 			format = timeFormatOld
 		}
-		ns := normal
-		if choice.IsDir() {
-			ns = bold
-			if strings.HasPrefix(choice.Name(), ".") {
-				ns = specialbold
-			}
-		} else if strings.HasPrefix(choice.Name(), ".") {
-			ns = special
-		}
-		s = baseStyle.Render(fmt.Sprintf("%s %11s %10d %s %s", checked, info.Mode(), info.Size(), info.ModTime().Format(format), ns.Render(choice.Name())))
+		s = baseStyle.Render(fmt.Sprintf("%s %11s %10d %s %s%s", checked, info.Mode(), info.Size(), info.ModTime().Format(format), fsv.renderName(choice.Name(), ns), fsv.renderLink(choice.Name(), baseStyle)))
+	} else if width < compactWidth {
+		s = baseStyle.Render(fmt.Sprintf("%s %10s %s", checked, "?", fsv.renderName(choice.Name(), ns)))
 	} else {
-		ns := normal
-		if choice.IsDir() {
-			ns = bold
-			if strings.HasPrefix(choice.Name(), ".") {
-				ns = specialbold
-			}
-		} else if strings.HasPrefix(choice.Name(), ".") {
-			ns = special
-		}
-		s = baseStyle.Render(fmt.Sprintf("%s %11s %10d %s %s", checked, "?", 0, "", ns.Render(choice.Name())))
+		s = baseStyle.Render(fmt.Sprintf("%s %11s %10d %s %s", checked, "?", 0, "", fsv.renderName(choice.Name(), ns)))
 	}
 	return s
 }
 
+// nameStyle returns the style for the name of the entry at index i, which
+// depends on its type and whether it is hidden or selected. Names are
+// colored as LS_COLORS says, if it has been loaded.
+func (fsv FS) nameStyle(i int) lipgloss.Style {
+	entry, info := fsv.entries[i], fsv.infos[i]
+	switch {
+	case fsv.selected[i]:
+		return colors.Selected
+	case info == nil:
+		return colors.Error
+	}
+	l, isLink := fsv.links[entry.Name()]
+	broken := isLink && l.info == nil
+	if lsColors != nil {
+		if s, ok := lsColors.style(entry.Name(), info.Mode(), broken); ok {
+			return s
+		}
+	}
+	hidden := strings.HasPrefix(entry.Name(), ".")
+	switch {
+	case entry.Type()&fs.ModeSymlink != 0 && broken:
+		return colors.Error
+	case entry.Type()&fs.ModeSymlink != 0:
+		return colors.Symlink
+	case entry.IsDir() && hidden:
+		return colors.HiddenDirectory
+	case entry.IsDir():
+		return colors.Directory
+	case hidden:
+		return colors.Hidden
+	case info.Mode().IsRegular() && info.Mode()&0o111 != 0:
+		return colors.Executable
+	}
+	return colors.Text
+}
+
 // Footer formats the footer using the base style and view width.
 func (fsv FS) Footer(i, width int, baseStyle lipgloss.Style) string {
 	sel := 0
@@ -145,7 +226,15 @@ func (fsv FS) Footer(i, width int, baseStyle lipgloss.Style) string {
 			sel++
 		}
 	}
-	return baseStyle.Render(fmt.Sprintf("? for help    %d / %d selected", sel, len(fsv.entries)))
+	order := "sorted by " + fsv.order.String()
+	if fsv.reverse {
+		order += ", reversed"
+	}
+	shown := ""
+	if fsv.filter != "" {
+		shown = fmt.Sprintf("    %d of %d shown (filter %q)", len(fsv.shown), len(fsv.entries), fsv.filter)
+	}
+	return baseStyle.Render(fmt.Sprintf("? for help    %s    %d / %d selected%s", order, sel, len(fsv.entries), shown))
 }
 
 // Init initializes a new file system view.
@@ -158,6 +247,15 @@ func (fsv *FS) Init(fsys fs.FS, root, folder string) error {
 	if err != nil {
 		return err
 	}
+	if !ShowHidden {
+		visible := entries[:0]
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), ".") {
+				visible = append(visible, entry)
+			}
+		}
+		entries = visible
+	}
 
 	fsv.entries = entries
 	fsv.infos = make([]fs.FileInfo, len(entries))
@@ -167,12 +265,18 @@ func (fsv *FS) Init(fsys fs.FS, root, folder string) error {
 			fsv.infos[i] = info
 		}
 	}
+	fsv.links = readLinks(fsys, rf, entries)
 	fsv.selected = make([]bool, len(entries))
 	fsv.root = root
 	fsv.folder = folder
 	fsv.fsys = fsys
 
-	sort.Sort(sortByExt(*fsv))
+	fsv.order, fsv.reverse = DefaultSort, DefaultReverse
+	if s, ok := folderSorts[fsv.Title()]; ok {
+		fsv.order, fsv.reverse = s.order, s.reverse
+	}
+	fsv.sort(fsv.order, fsv.reverse)
+	fsv.applyFilter()
 
 	return nil
 }
@@ -0,0 +1,83 @@
+package views
+
+import (
+	"path"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// matched is the style for the part of a name that matches the filter.
+var matched = lipgloss.NewStyle().Underline(true).Bold(true)
+
+// SetFilter shows only the entries whose names match the filter. A filter
+// containing *, ? or [ is a glob pattern; otherwise it matches any part of
+// the name. Matching ignores case. An empty filter shows all entries.
+// Selections are kept for entries that are filtered out.
+func (fsv *FS) SetFilter(filter string) {
+	fsv.filter = filter
+	fsv.applyFilter()
+}
+
+// Filter returns the current filter.
+func (fsv FS) Filter() string {
+	return fsv.filter
+}
+
+// applyFilter finds the entries that match the filter.
+func (fsv *FS) applyFilter() {
+	fsv.shown = fsv.shown[:0]
+	if fsv.filter == "" {
+		return
+	}
+	for i, entry := range fsv.entries {
+		if _, _, ok := fsv.match(entry.Name()); ok {
+			fsv.shown = append(fsv.shown, i)
+		}
+	}
+}
+
+// pos converts a position in the list shown into a position in the
+// full list of entries. It returns -1 if i is out of range.
+func (fsv FS) pos(i int) int {
+	if fsv.filter != "" {
+		if i >= 0 && i < len(fsv.shown) {
+			return fsv.shown[i]
+		}
+		return -1
+	}
+	if i >= 0 && i < len(fsv.entries) {
+		return i
+	}
+	return -1
+}
+
+// match reports whether name matches the filter, and which part of
+// the name matched.
+func (fsv FS) match(name string) (int, int, bool) {
+	if fsv.filter == "" {
+		return 0, 0, false
+	}
+	lname := strings.ToLower(name)
+	lfilter := strings.ToLower(fsv.filter)
+	if strings.ContainsAny(lfilter, "*?[") {
+		ok, err := path.Match(lfilter, lname)
+		return 0, len(name), ok && err == nil
+	}
+	i := strings.Index(lname, lfilter)
+	if i < 0 || len(lname) != len(name) {
+		// Fall back to the whole name if case folding changed its length
+		return 0, len(name), i >= 0
+	}
+	return i, i + len(lfilter), true
+}
+
+// renderName renders a file name in the given style, highlighting the
+// part that matches the filter.
+func (fsv FS) renderName(name string, style lipgloss.Style) string {
+	start, end, ok := fsv.match(name)
+	if !ok {
+		return style.Render(name)
+	}
+	return style.Render(name[:start]) + matched.Inherit(style).Render(name[start:end]) + style.Render(name[end:])
+}
@@ -0,0 +1,164 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ancientlore/hermit2/theme"
+	"charm.land/lipgloss/v2"
+)
+
+// maxLinks is the most links followed when finding the real location of
+// a path, as a guard against loops that grow the path.
+const maxLinks = 255
+
+// ErrLinkLoop is returned when links lead to each other in a loop.
+var ErrLinkLoop = errors.New("symbolic links form a loop")
+
+// link describes where a symbolic link leads.
+type link struct {
+	target string      // Destination as stored in the link
+	info   fs.FileInfo // What the link leads to, or nil if it is broken
+	err    error       // Why the link is broken
+}
+
+// readLinks finds where the symbolic links among the entries lead.
+func readLinks(fsys fs.FS, dir string, entries []fs.DirEntry) map[string]link {
+	links := make(map[string]link)
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+		p := path.Join(dir, entry.Name())
+		var l link
+		l.target, _ = fs.ReadLink(fsys, p)
+		l.info, l.err = fs.Stat(fsys, p)
+		links[entry.Name()] = l
+	}
+	return links
+}
+
+// isFolder reports whether entry i is a folder or a symbolic link to one,
+// so that both are sorted with the folders.
+func (fsv FS) isFolder(i int) bool {
+	if l, ok := fsv.links[fsv.entries[i].Name()]; ok {
+		return l.info != nil && l.info.IsDir()
+	}
+	return fsv.entries[i].IsDir()
+}
+
+// Resolved returns the entry at position i, or for a symbolic link, an
+// entry for what it leads to under the name of the link. It returns an
+// error if the link is broken.
+func (fsv FS) Resolved(i int) (fs.DirEntry, error) {
+	entry := fsv.At(i)
+	if entry == nil {
+		return nil, nil
+	}
+	l, ok := fsv.links[entry.Name()]
+	if !ok {
+		return entry, nil
+	}
+	if l.info == nil {
+		return nil, fmt.Errorf("link %s to %s is broken: %w", entry.Name(), l.target, l.err)
+	}
+	return fs.FileInfoToDirEntry(l.info), nil
+}
+
+// IsLink reports whether the entry at position i is a symbolic link.
+func (fsv FS) IsLink(i int) bool {
+	entry := fsv.At(i)
+	if entry == nil {
+		return false
+	}
+	_, ok := fsv.links[entry.Name()]
+	return ok
+}
+
+// RealPath returns the folder path of where the entry at position i really
+// is, with all symbolic links along the way followed.
+func (fsv FS) RealPath(i int) (string, error) {
+	entry := fsv.At(i)
+	if entry == nil {
+		return "", fs.ErrNotExist
+	}
+	return realPath(fsv.fsys, fsv.root, path.Join(fsv.folder, entry.Name()))
+}
+
+// realPath follows the symbolic links in the folder path p, one part at a
+// time, and returns the path it really names. Absolute link targets are
+// taken to be on the file system named by root.
+func realPath(fsys fs.FS, root, p string) (string, error) {
+	var (
+		done []string // Parts already resolved
+		rest = strings.Split(strings.TrimPrefix(p, "/"), "/")
+		seen = make(map[string]bool)
+		hops int
+	)
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(done) > 0 {
+				done = done[:len(done)-1]
+			}
+			continue
+		}
+		cur := path.Join(append(done, part)...)
+		info, err := fs.Lstat(fsys, cur)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			done = append(done, part)
+			continue
+		}
+
+		// Following the same link with the same path left to resolve
+		// means the links are in a loop
+		key := cur + "\x00" + strings.Join(rest, "/")
+		hops++
+		if seen[key] || hops > maxLinks {
+			return "", fmt.Errorf("/%s: %w", cur, ErrLinkLoop)
+		}
+		seen[key] = true
+
+		target, err := fs.ReadLink(fsys, cur)
+		if err != nil {
+			return "", err
+		}
+		if vol := filepath.VolumeName(target); vol != "" {
+			if !strings.EqualFold(vol, filepath.VolumeName(root)) {
+				return "", fmt.Errorf("/%s leads to another drive (%s)", cur, target)
+			}
+			target = strings.TrimPrefix(target, vol)
+		}
+		target = filepath.ToSlash(target)
+		if path.IsAbs(target) {
+			done = nil
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return "/" + path.Join(done...), nil
+}
+
+// renderLink renders where the symbolic link named name leads, if it is
+// one, in the style of the row. Broken links are marked.
+func (fsv FS) renderLink(name string, row lipgloss.Style) string {
+	l, ok := fsv.links[name]
+	if !ok {
+		return ""
+	}
+	plain := row.UnsetWidth().UnsetMaxWidth().UnsetHeight()
+	if l.info == nil {
+		return plain.Render(" -> ") + theme.Row(colors.Error, row).Render(l.target+" (broken)")
+	}
+	return plain.Render(" -> " + l.target)
+}
@@ -1,7 +1,9 @@
 package views
 
 import (
+	"fmt"
 	"path"
+	"sort"
 	"strings"
 )
 
@@ -9,9 +11,9 @@ type sortByName FS
 
 func (e sortByName) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
 	}
 	// File/dir sort next
@@ -32,9 +34,9 @@ type sortByNameRev FS
 
 func (e sortByNameRev) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
 	}
 	// File/dir sort next
@@ -55,11 +57,11 @@ type sortByExt FS
 
 func (e sortByExt) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
-	} else if e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return e.entries[i].Name() < e.entries[j].Name()
 	}
 	// Special files next
@@ -96,9 +98,9 @@ type sortByExtRev FS
 
 func (e sortByExtRev) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
 	}
 	// Special files next
@@ -110,7 +112,7 @@ func (e sortByExtRev) Less(i, j int) bool {
 		return false
 	}
 	// Directory name next
-	if e.entries[i].IsDir() && e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return e.entries[j].Name() < e.entries[i].Name()
 	}
 	// Extensions next
@@ -139,11 +141,11 @@ type sortBySize FS
 
 func (e sortBySize) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
-	} else if e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return e.entries[i].Name() < e.entries[j].Name()
 	}
 	// Size next
@@ -179,11 +181,11 @@ type sortBySizeRev FS
 
 func (e sortBySizeRev) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
-	} else if e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return e.entries[i].Name() < e.entries[j].Name()
 	}
 	// Size next
@@ -219,9 +221,9 @@ type sortByDate FS
 
 func (e sortByDate) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
 	}
 	// Special files next
@@ -265,9 +267,9 @@ type sortByDateRev FS
 
 func (e sortByDateRev) Less(i, j int) bool {
 	// Directories first
-	if e.entries[i].IsDir() && !e.entries[j].IsDir() {
+	if FS(e).isFolder(i) && !FS(e).isFolder(j) {
 		return true
-	} else if !e.entries[i].IsDir() && e.entries[j].IsDir() {
+	} else if !FS(e).isFolder(i) && FS(e).isFolder(j) {
 		return false
 	}
 	// Special files next
@@ -306,3 +308,101 @@ func (e sortByDateRev) Swap(i, j int) {
 	e.infos[i], e.infos[j] = e.infos[j], e.infos[i]
 	e.selected[i], e.selected[j] = e.selected[j], e.selected[i]
 }
+
+// SortOrder identifies the order of entries in a folder listing.
+type SortOrder int
+
+// Supported sort orders. Folders are always listed first.
+const (
+	SortByName SortOrder = iota // Sort by name
+	SortByExt                   // Sort by extension, then name
+	SortBySize                  // Sort by size, then name
+	SortByDate                  // Sort by modification time, then name
+)
+
+// String returns the name of the sort order.
+func (o SortOrder) String() string {
+	switch o {
+	case SortByName:
+		return "name"
+	case SortBySize:
+		return "size"
+	case SortByDate:
+		return "date"
+	}
+	return "ext"
+}
+
+// Next returns the sort order that follows o, wrapping around
+// after the last one.
+func (o SortOrder) Next() SortOrder {
+	return (o + 1) % (SortByDate + 1)
+}
+
+// sortSetting is a sort order and direction.
+type sortSetting struct {
+	order   SortOrder
+	reverse bool
+}
+
+// folderSorts remembers the sort chosen for each folder during the session.
+var folderSorts = make(map[string]sortSetting)
+
+// SetSort sorts the entries using the given order and remembers the choice
+// for the folder. Selections stay with their entries.
+func (fsv *FS) SetSort(order SortOrder, reverse bool) {
+	fsv.order = order
+	fsv.reverse = reverse
+	folderSorts[fsv.Title()] = sortSetting{order, reverse}
+	fsv.sort(order, reverse)
+	fsv.applyFilter()
+}
+
+// Sort returns the current sort order and whether it is reversed.
+func (fsv FS) Sort() (SortOrder, bool) {
+	return fsv.order, fsv.reverse
+}
+
+// ParseSortOrder converts the name of a sort order, as used in the
+// configuration file, into a SortOrder.
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch s {
+	case "name":
+		return SortByName, nil
+	case "ext":
+		return SortByExt, nil
+	case "size":
+		return SortBySize, nil
+	case "date":
+		return SortByDate, nil
+	}
+	return SortByExt, fmt.Errorf("unknown sort order %q", s)
+}
+
+// sort sorts the entries using the given order.
+func (fsv *FS) sort(order SortOrder, reverse bool) {
+	var s sort.Interface
+	switch order {
+	case SortByName:
+		s = sortByName(*fsv)
+		if reverse {
+			s = sortByNameRev(*fsv)
+		}
+	case SortBySize:
+		s = sortBySize(*fsv)
+		if reverse {
+			s = sortBySizeRev(*fsv)
+		}
+	case SortByDate:
+		s = sortByDate(*fsv)
+		if reverse {
+			s = sortByDateRev(*fsv)
+		}
+	default:
+		s = sortByExt(*fsv)
+		if reverse {
+			s = sortByExtRev(*fsv)
+		}
+	}
+	sort.Sort(s)
+}
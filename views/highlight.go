@@ -0,0 +1,154 @@
+package views
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/charmbracelet/colorprofile"
+)
+
+// analyseSize is the number of bytes at the start of a file used to guess
+// its language when its name does not tell.
+const analyseSize = 4096
+
+var (
+	formatter = formatters.TTY256 // Formats highlighted text for the terminal
+	style     *chroma.Style       // Colors of highlighted text
+	overrides []lexerOverride     // Lexers chosen by file name
+)
+
+// lexerOverride picks a lexer for files whose names match a pattern.
+type lexerOverride struct {
+	pattern string
+	lexer   chroma.Lexer
+}
+
+// SetColorProfile chooses how highlighted text is colored to suit the
+// colors the terminal supports.
+func SetColorProfile(p colorprofile.Profile) {
+	switch p {
+	case colorprofile.TrueColor:
+		formatter = formatters.TTY16m
+	case colorprofile.ANSI256:
+		formatter = formatters.TTY256
+	case colorprofile.ANSI:
+		formatter = formatters.TTY16
+	default:
+		formatter = formatters.NoOp
+	}
+}
+
+// SetStyle chooses the chroma style used to highlight text. It may be a
+// built-in style or one added with DefineStyle.
+func SetStyle(name string) error {
+	if _, ok := styles.Registry[name]; !ok {
+		return fmt.Errorf("unknown highlight style %q", name)
+	}
+	style = styles.Get(name)
+	return nil
+}
+
+// DefineStyle adds a chroma style. The entries map token type names, such as
+// "Keyword" or "NameFunction", to chroma style entries, such as "bold #6ab825".
+func DefineStyle(name string, entries map[string]string) error {
+	types := make(map[string]chroma.TokenType)
+	for t := range chroma.StandardTypes {
+		types[t.String()] = t
+	}
+	se := make(chroma.StyleEntries)
+	for _, k := range slices.Sorted(maps.Keys(entries)) {
+		t, ok := types[k]
+		if !ok {
+			return fmt.Errorf("unknown token type %q in highlight style %q", k, name)
+		}
+		se[t] = entries[k]
+	}
+	s, err := chroma.NewStyle(name, se)
+	if err != nil {
+		return fmt.Errorf("invalid highlight style %q: %w", name, err)
+	}
+	styles.Register(s)
+	return nil
+}
+
+// SetLexer chooses the lexer for files whose names match the pattern, as
+// in path.Match. Patterns are tried in the order they are set, before the
+// lexers' own file name patterns.
+func SetLexer(pattern, name string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid file name pattern %q: %w", pattern, err)
+	}
+	l := lexers.Get(name)
+	if l == nil {
+		return fmt.Errorf("unknown lexer %q", name)
+	}
+	overrides = append(overrides, lexerOverride{pattern: pattern, lexer: l})
+	return nil
+}
+
+// matchLexer finds the lexer for a file from its name, or else from the
+// text at its start. It returns nil if the language is not known.
+func matchLexer(fpath string, idx *lineIndex) chroma.Lexer {
+	name := path.Base(fpath)
+	for _, o := range overrides {
+		if ok, _ := path.Match(o.pattern, name); ok {
+			return o.lexer
+		}
+	}
+	if l := lexers.Match(fpath); l != nil {
+		return l
+	}
+	return lexers.Analyse(idx.read(0, min(idx.size, analyseSize)))
+}
+
+func init() {
+	style = styles.Register(chroma.MustNewStyle("hermit", chroma.StyleEntries{
+		chroma.Background:         "#d0d0d0 bg: ", //"#d0d0d0 bg:#202020",
+		chroma.TextWhitespace:     "#666666",
+		chroma.Comment:            "italic #999999",
+		chroma.CommentPreproc:     "noitalic bold #cd2828",
+		chroma.CommentSpecial:     "noitalic bold #e50808 bg: ", // "noitalic bold #e50808 bg:#520000",
+		chroma.Keyword:            "bold #6ab825",
+		chroma.KeywordPseudo:      "nobold",
+		chroma.OperatorWord:       "bold #6ab825",
+		chroma.LiteralString:      "#ed9d13",
+		chroma.LiteralStringOther: "#ffa500",
+		chroma.LiteralNumber:      "#3677a9",
+		chroma.NameBuiltin:        "#24909d",
+		chroma.NameVariable:       "#40ffff",
+		chroma.NameConstant:       "#40ffff",
+		chroma.NameClass:          "underline #447fcf",
+		chroma.NameFunction:       "#447fcf",
+		chroma.NameNamespace:      "underline #447fcf",
+		chroma.NameException:      "#bbbbbb",
+		chroma.NameTag:            "bold #6ab825",
+		chroma.NameAttribute:      "#bbbbbb",
+		chroma.NameDecorator:      "#ffa500",
+		chroma.GenericHeading:     "bold #ffffff",
+		chroma.GenericSubheading:  "underline #ffffff",
+		chroma.GenericDeleted:     "#d22323",
+		chroma.GenericInserted:    "#589819",
+		chroma.GenericError:       "#d22323",
+		chroma.GenericEmph:        "italic",
+		chroma.GenericStrong:      "bold",
+		chroma.GenericPrompt:      "#aaaaaa",
+		chroma.GenericOutput:      "#cccccc",
+		chroma.GenericTraceback:   "#d22323",
+		chroma.GenericUnderline:   "underline",
+		chroma.Error:              "#a61717 bg: ", // "bg:#e3d2d2 #a61717",
+	}))
+
+	/*
+		s := styles.Get("hermit")
+		for _, t := range s.Types() {
+			e := s.Get(t)
+			fmt.Println(t, e)
+		}
+	*/
+}
@@ -0,0 +1,52 @@
+package views
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ancientlore/hermit2/jobs"
+	"charm.land/lipgloss/v2"
+)
+
+// Jobs is a viewer for the background jobs of a job manager.
+type Jobs struct {
+	mgr *jobs.Manager
+}
+
+// NewJobs creates a viewer for the jobs in mgr.
+func NewJobs(mgr *jobs.Manager) Jobs {
+	return Jobs{mgr: mgr}
+}
+
+// At returns the job at position i, or nil.
+func (v Jobs) At(i int) *jobs.Job {
+	a := v.mgr.Jobs()
+	if i >= 0 && i < len(a) {
+		return a[i]
+	}
+	return nil
+}
+
+// Render formats the line at position i using the base style and view width.
+func (v Jobs) Render(i, width int, baseStyle lipgloss.Style) string {
+	j := v.At(i)
+	if j == nil {
+		return baseStyle.Render("")
+	}
+	return baseStyle.Render(fmt.Sprintf("%4d %-8s %8s  %s", j.ID, j.State(), j.Elapsed().Round(time.Second), j.Status(20)))
+}
+
+// Footer formats the footer using the base style and view width.
+func (v Jobs) Footer(cursor, width int, baseStyle lipgloss.Style) string {
+	return baseStyle.Render(fmt.Sprintf("%d running / %d jobs", len(v.mgr.Running()), len(v.mgr.Jobs())))
+}
+
+// Len returns the number of jobs.
+func (v Jobs) Len(width int) int {
+	return len(v.mgr.Jobs())
+}
+
+// Close closes the viewer, if necessary.
+func (v Jobs) Close() error {
+	return nil
+}
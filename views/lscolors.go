@@ -0,0 +1,293 @@
+package views
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// lsColors colors file names by type and extension, as given by the
+// LS_COLORS environment variable or a dircolors file. It is nil unless
+// one has been loaded.
+var lsColors *fileColors
+
+// fileColors holds the styles of file types and extensions.
+type fileColors struct {
+	types    map[string]lipgloss.Style // By two-letter type, such as "di"
+	suffixes []suffixStyle             // By name suffix, longest first
+}
+
+// suffixStyle is the style of names that end with a suffix.
+type suffixStyle struct {
+	suffix string // Lower case, as matching ignores case
+	style  lipgloss.Style
+}
+
+// dircolorsTypes maps the type names of a dircolors file to the codes
+// used in LS_COLORS.
+var dircolorsTypes = map[string]string{
+	"NORMAL":                "no",
+	"NORM":                  "no",
+	"FILE":                  "fi",
+	"RESET":                 "rs",
+	"DIR":                   "di",
+	"LNK":                   "ln",
+	"LINK":                  "ln",
+	"SYMLINK":               "ln",
+	"ORPHAN":                "or",
+	"MISSING":               "mi",
+	"FIFO":                  "pi",
+	"PIPE":                  "pi",
+	"SOCK":                  "so",
+	"DOOR":                  "do",
+	"BLK":                   "bd",
+	"BLOCK":                 "bd",
+	"CHR":                   "cd",
+	"CHAR":                  "cd",
+	"EXEC":                  "ex",
+	"SETUID":                "su",
+	"SETGID":                "sg",
+	"CAPABILITY":            "ca",
+	"STICKY":                "st",
+	"OTHER_WRITABLE":        "ow",
+	"OWR":                   "ow",
+	"STICKY_OTHER_WRITABLE": "tw",
+	"OWT":                   "tw",
+	"MULTIHARDLINK":         "mh",
+}
+
+// SetLSColors colors file names as described by the value of an LS_COLORS
+// environment variable. An empty value turns this off.
+func SetLSColors(s string) error {
+	if s == "" {
+		lsColors = nil
+		return nil
+	}
+	c := &fileColors{types: make(map[string]lipgloss.Style)}
+	for _, entry := range strings.Split(s, ":") {
+		if entry == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid LS_COLORS entry %q", entry)
+		}
+		if err := c.add(k, v); err != nil {
+			return err
+		}
+	}
+	c.sort()
+	lsColors = c
+	return nil
+}
+
+// LoadDircolors colors file names as described by a file in the format read
+// by dircolors(1). Terminal-specific sections are not supported; all
+// entries are used.
+func LoadDircolors(r io.Reader) error {
+	c := &fileColors{types: make(map[string]lipgloss.Style)}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		k, v := fields[0], fields[1]
+		switch {
+		case strings.HasPrefix(k, "."):
+			k = "*" + k
+		case strings.HasPrefix(k, "*"):
+		default:
+			code, ok := dircolorsTypes[strings.ToUpper(k)]
+			if !ok {
+				// TERM, COLORTERM, COLOR, OPTIONS and the like
+				continue
+			}
+			k = code
+		}
+		if err := c.add(k, v); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	c.sort()
+	lsColors = c
+	return nil
+}
+
+// add adds the style for a type or suffix pattern.
+func (c *fileColors) add(k, v string) error {
+	style, err := parseSGR(v)
+	if err != nil {
+		return fmt.Errorf("invalid colors for %q: %w", k, err)
+	}
+	if suffix, ok := strings.CutPrefix(k, "*"); ok {
+		c.suffixes = append(c.suffixes, suffixStyle{suffix: strings.ToLower(suffix), style: style})
+	} else {
+		c.types[k] = style
+	}
+	return nil
+}
+
+// sort orders the suffixes so that the longest match is found first.
+func (c *fileColors) sort() {
+	// A stable insertion sort keeps later entries after earlier ones of
+	// the same length, and the lists are short
+	for i := 1; i < len(c.suffixes); i++ {
+		for j := i; j > 0 && len(c.suffixes[j].suffix) > len(c.suffixes[j-1].suffix); j-- {
+			c.suffixes[j], c.suffixes[j-1] = c.suffixes[j-1], c.suffixes[j]
+		}
+	}
+}
+
+// style returns the style of a file name the way ls does: by type, and for
+// plain files, by suffix. It returns false if no style applies.
+func (c *fileColors) style(name string, mode fs.FileMode, broken bool) (lipgloss.Style, bool) {
+	var t string
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		t = "ln"
+		if broken {
+			if _, ok := c.types["or"]; ok {
+				t = "or"
+			}
+		}
+	case mode.IsDir():
+		sticky, writable := mode&fs.ModeSticky != 0, mode&0o002 != 0
+		switch {
+		case sticky && writable:
+			t = "tw"
+		case writable:
+			t = "ow"
+		case sticky:
+			t = "st"
+		default:
+			t = "di"
+		}
+	case mode&fs.ModeNamedPipe != 0:
+		t = "pi"
+	case mode&fs.ModeSocket != 0:
+		t = "so"
+	case mode&fs.ModeCharDevice != 0:
+		t = "cd"
+	case mode&fs.ModeDevice != 0:
+		t = "bd"
+	case mode&fs.ModeSetuid != 0:
+		t = "su"
+	case mode&fs.ModeSetgid != 0:
+		t = "sg"
+	case mode&0o111 != 0:
+		t = "ex"
+	default:
+		lower := strings.ToLower(name)
+		for _, s := range c.suffixes {
+			if strings.HasSuffix(lower, s.suffix) {
+				return s.style, true
+			}
+		}
+		t = "fi"
+	}
+	if s, ok := c.types[t]; ok {
+		return s, true
+	}
+	// Special directories and files fall back to the plain kind
+	switch t {
+	case "tw", "ow", "st":
+		t = "di"
+	case "su", "sg", "ex":
+		t = "fi"
+	}
+	s, ok := c.types[t]
+	return s, ok
+}
+
+// parseSGR converts a list of SGR codes, such as "01;34" or "38;5;208",
+// into a style.
+func parseSGR(s string) (lipgloss.Style, error) {
+	style := lipgloss.NewStyle()
+	if s == "" || s == "target" {
+		// "target" colors links like what they point to, which is not
+		// supported, so they keep the plain style
+		return style, nil
+	}
+	var codes []int
+	for _, f := range strings.Split(s, ";") {
+		if f == "" {
+			codes = append(codes, 0)
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return style, fmt.Errorf("invalid code %q", f)
+		}
+		codes = append(codes, n)
+	}
+	for i := 0; i < len(codes); i++ {
+		switch n := codes[i]; {
+		case n == 0:
+			style = lipgloss.NewStyle()
+		case n == 1:
+			style = style.Bold(true)
+		case n == 2:
+			style = style.Faint(true)
+		case n == 3:
+			style = style.Italic(true)
+		case n == 4:
+			style = style.Underline(true)
+		case n == 5 || n == 6:
+			style = style.Blink(true)
+		case n == 7:
+			style = style.Reverse(true)
+		case n == 9:
+			style = style.Strikethrough(true)
+		case n >= 30 && n <= 37:
+			style = style.Foreground(basicColor(n - 30))
+		case n >= 40 && n <= 47:
+			style = style.Background(basicColor(n - 40))
+		case n >= 90 && n <= 97:
+			style = style.Foreground(basicColor(n - 90 + 8))
+		case n >= 100 && n <= 107:
+			style = style.Background(basicColor(n - 100 + 8))
+		case n == 38 || n == 48:
+			c, used, err := extendedColor(codes[i+1:])
+			if err != nil {
+				return style, err
+			}
+			if n == 38 {
+				style = style.Foreground(c)
+			} else {
+				style = style.Background(c)
+			}
+			i += used
+		}
+	}
+	return style, nil
+}
+
+// basicColor returns one of the 16 standard terminal colors.
+func basicColor(n int) color.Color {
+	return lipgloss.Color(strconv.Itoa(n))
+}
+
+// extendedColor parses the codes after 38 or 48: either 5 and a color
+// number, or 2 and red, green and blue values. It returns how many codes
+// it used.
+func extendedColor(codes []int) (color.Color, int, error) {
+	switch {
+	case len(codes) >= 2 && codes[0] == 5:
+		return lipgloss.Color(strconv.Itoa(codes[1])), 2, nil
+	case len(codes) >= 4 && codes[0] == 2:
+		return lipgloss.Color(fmt.Sprintf("#%02X%02X%02X", codes[1], codes[2], codes[3])), 4, nil
+	}
+	return nil, 0, errors.New("invalid extended color")
+}
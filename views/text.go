@@ -1,105 +1,281 @@
 package views
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/ancientlore/hermit2/scroller"
 	"github.com/alecthomas/chroma"
-	"github.com/alecthomas/chroma/lexers"
-	"github.com/alecthomas/chroma/quick"
-	"github.com/alecthomas/chroma/styles"
 	"charm.land/lipgloss/v2"
 	"github.com/huandu/xstrings"
 )
 
-// Text manages the base logic of the cursor position and pagination of a string of text.
-type Text []string
+// Text is a viewer for lines of text. Lines are read only when they are
+// rendered and are highlighted as they are shown, so files larger than
+// memory can be viewed. The lines of a file are indexed in the background;
+// until that is done, the lines found so far are followed by a marker line
+// and then the lines at the end of the file.
+type Text struct {
+	idx     *lineIndex
+	closer  io.Closer        // Closes the file, if any
+	lexer   chroma.Lexer     // Highlights lines, if the type of file is known
+	cache   map[int64]string // Highlighted lines by offset
+	match   *regexp.Regexp   // Search matches to highlight, if any
+	wrap    *wrapTable       // Visual lines, if long lines are wrapped
+	pan     int              // Columns scrolled sideways, if not wrapped
+	numbers bool             // Whether line numbers are shown
+}
+
+// maxCached is the number of highlighted lines kept.
+const maxCached = 1000
+
+var (
+	marker = lipgloss.NewStyle().Faint(true)
+	found  = lipgloss.NewStyle().Reverse(true)
+)
 
 // Render formats the line at position i using the base style and view width.
 func (v Text) Render(i, width int, baseStyle lipgloss.Style) string {
-	if i >= 0 && i < len(v) {
-		return baseStyle.Render(v[i])
+	head, _, done, _ := v.idx.status()
+	num := v.renderGutter(i, width)
+	if num != "" {
+		w := v.textWidth(width)
+		baseStyle = baseStyle.Width(w).MaxWidth(w)
+	}
+	if !done && i == head {
+		return num + baseStyle.Render(marker.Render(fmt.Sprintf("… indexing, %d lines so far …", head)))
+	}
+	start, end, ok := v.bounds(v.LineOf(i, width))
+	if !ok {
+		return ""
+	}
+	if v.match != nil {
+		// Lines with matches are shown without syntax highlighting
+		line := v.read(start, end)
+		if loc := v.match.FindAllStringIndex(line, -1); len(loc) > 0 {
+			return num + baseStyle.Render(v.cut(markMatches(line, loc), i, width))
+		}
+	}
+	s, ok := v.cache[start]
+	if !ok {
+		s = v.highlight(v.read(start, end))
+		if len(v.cache) >= maxCached {
+			clear(v.cache)
+		}
+		v.cache[start] = s
+	}
+	return num + baseStyle.Render(v.cut(s, i, width))
+}
+
+// Line returns the text of the line at position i, with tabs expanded.
+func (v Text) Line(i int) (string, bool) {
+	start, end, ok := v.bounds(i)
+	if !ok {
+		return "", false
+	}
+	return v.read(start, end), true
+}
+
+// bounds returns where the line at position i starts and ends.
+func (v Text) bounds(i int) (int64, int64, bool) {
+	head, _, done, _ := v.idx.status()
+	if done || i < head {
+		return v.idx.bounds(i, false)
+	}
+	return v.idx.bounds(i-head-1, true)
+}
+
+// read reads a line and expands its tabs.
+func (v Text) read(start, end int64) string {
+	return xstrings.ExpandTabs(strings.ReplaceAll(v.idx.read(start, end), "\r", ""), 8)
+}
+
+// highlight colors a line according to the type of file.
+func (v Text) highlight(s string) string {
+	if v.lexer == nil {
+		return s
+	}
+	it, err := v.lexer.Tokenise(nil, s)
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	if err := formatter.Format(&b, style, it); err != nil {
+		return s
+	}
+	return strings.ReplaceAll(b.String(), "\n", "")
+}
+
+// markMatches highlights the matches at the given locations in s.
+func markMatches(s string, loc [][]int) string {
+	var b strings.Builder
+	prev := 0
+	for _, l := range loc {
+		if l[1] == l[0] {
+			continue
+		}
+		b.WriteString(s[prev:l[0]])
+		b.WriteString(found.Render(s[l[0]:l[1]]))
+		prev = l[1]
+	}
+	b.WriteString(s[prev:])
+	return b.String()
+}
+
+// Search returns the position of the next line after from, or the previous
+// one before it, that matches the query, or -1 if there is none.
+func (v Text) Search(ctx context.Context, q scroller.Query, from, width int, backward bool) (int, error) {
+	re, err := q.Regexp()
+	if err != nil {
+		return -1, err
+	}
+	step, end := 1, v.lines()
+	if backward {
+		step, end = -1, -1
+	}
+	for i := v.LineOf(from, width) + step; i != end; i += step {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		if line, ok := v.Line(i); ok && re.MatchString(line) {
+			return v.PosOf(i, width), nil
+		}
+	}
+	return -1, nil
+}
+
+// SetHighlight sets the query whose matches are highlighted.
+func (v *Text) SetHighlight(q scroller.Query) {
+	v.match = nil
+	if q.Pattern != "" {
+		v.match, _ = q.Regexp()
+	}
+}
+
+// Targets describes the positions Locate accepts.
+func (v Text) Targets() string {
+	return "line number or percentage"
+}
+
+// Locate returns the position of a line given by its number, counting from
+// one, or by a percentage of the way through the file.
+func (v Text) Locate(target string, width int) (int, error) {
+	head, _, done, _ := v.idx.status()
+	p, ok, err := scroller.ParsePercent(target)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		if !done {
+			return 0, errors.New("percentages can be used once indexing is done")
+		}
+		return v.PosOf(int(math.Round(p/100*float64(head-1))), width), nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(target))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid line number %q", target)
+	}
+	if !done && n > head {
+		return 0, fmt.Errorf("line %d has not been indexed yet", n)
+	}
+	return v.PosOf(min(n, head)-1, width), nil
+}
+
+// Indexed returns the number of lines indexed so far and whether
+// indexing is done.
+func (v Text) Indexed() (int, bool) {
+	head, _, done, _ := v.idx.status()
+	return head, done
+}
+
+// Language returns the name of the language the text is highlighted as.
+func (v Text) Language() string {
+	if v.lexer == nil {
+		return "plain text"
 	}
-	return ""
+	return v.lexer.Config().Name
 }
 
 // Footer formats the footer using the base style and view width.
 func (v Text) Footer(cursor, width int, baseStyle lipgloss.Style) string {
-	return baseStyle.Render(fmt.Sprintf("%d / %d", cursor+1, len(v)))
+	head, _, done, err := v.idx.status()
+	mode := "no wrap"
+	switch {
+	case v.Wrapping():
+		mode = "wrapping…"
+	case v.wrap != nil:
+		mode = "wrap"
+	case v.pan > 0:
+		mode = fmt.Sprintf("no wrap, column %d", v.pan+1)
+	}
+	switch {
+	case err != nil:
+		return baseStyle.Render(fmt.Sprintf("%d / %d lines, indexing stopped: %v", cursor+1, head, err))
+	case done:
+		line := v.LineOf(cursor, width) + 1
+		return baseStyle.Render(fmt.Sprintf("%d / %d  %d%%    %s    %s", line, head, line*100/max(head, 1), v.Language(), mode))
+	case cursor < head:
+		return baseStyle.Render(fmt.Sprintf("%d / %d+    indexing… %d lines so far    %s    %s", cursor+1, head, head, v.Language(), mode))
+	default:
+		return baseStyle.Render(fmt.Sprintf("end of file    indexing… %d lines so far", head))
+	}
 }
 
-// Len returns the number of lines of text.
+// Len returns the number of lines of text, counting visual lines if long
+// lines are wrapped.
 func (v Text) Len(width int) int {
-	return len(v)
+	if v.wrap != nil && width > 0 {
+		t := v.table(width)
+		return t.total + v.lines() - t.measured
+	}
+	return v.lines()
+}
+
+// lines returns the number of lines of text.
+func (v Text) lines() int {
+	head, tail, done, _ := v.idx.status()
+	if done {
+		return head
+	}
+	return head + 1 + tail
 }
 
-// Close closes the viewer, if necessary.
+// Close stops indexing and wrapping and closes the file, if necessary.
 func (v Text) Close() error {
+	v.idx.stop()
+	v.wrap.stop()
+	if v.closer != nil {
+		return v.closer.Close()
+	}
 	return nil
 }
 
-// NewText expands tabs and splits the string into a slice of lines.
+// NewText creates a viewer for a string of text.
 func NewText(t string, fpath string) Text {
+	return newText(newLineIndex(strings.NewReader(t), int64(len(t)), false), nil, fpath)
+}
 
-	var lexer string
-	if l := lexers.Match(fpath); l != nil {
-		lexer = l.Config().Name
-	}
-
-	s := xstrings.ExpandTabs(strings.ReplaceAll(t, "\r", ""), 8)
-
-	var buf bytes.Buffer
-	err := quick.Highlight(&buf, s, lexer, "terminal256", "hermit")
-	if err == nil {
-		s = buf.String()
-	}
-
-	return strings.Split(s, "\n")
-}
-
-func init() {
-	styles.Register(chroma.MustNewStyle("hermit", chroma.StyleEntries{
-		chroma.Background:         "#d0d0d0 bg: ", //"#d0d0d0 bg:#202020",
-		chroma.TextWhitespace:     "#666666",
-		chroma.Comment:            "italic #999999",
-		chroma.CommentPreproc:     "noitalic bold #cd2828",
-		chroma.CommentSpecial:     "noitalic bold #e50808 bg: ", // "noitalic bold #e50808 bg:#520000",
-		chroma.Keyword:            "bold #6ab825",
-		chroma.KeywordPseudo:      "nobold",
-		chroma.OperatorWord:       "bold #6ab825",
-		chroma.LiteralString:      "#ed9d13",
-		chroma.LiteralStringOther: "#ffa500",
-		chroma.LiteralNumber:      "#3677a9",
-		chroma.NameBuiltin:        "#24909d",
-		chroma.NameVariable:       "#40ffff",
-		chroma.NameConstant:       "#40ffff",
-		chroma.NameClass:          "underline #447fcf",
-		chroma.NameFunction:       "#447fcf",
-		chroma.NameNamespace:      "underline #447fcf",
-		chroma.NameException:      "#bbbbbb",
-		chroma.NameTag:            "bold #6ab825",
-		chroma.NameAttribute:      "#bbbbbb",
-		chroma.NameDecorator:      "#ffa500",
-		chroma.GenericHeading:     "bold #ffffff",
-		chroma.GenericSubheading:  "underline #ffffff",
-		chroma.GenericDeleted:     "#d22323",
-		chroma.GenericInserted:    "#589819",
-		chroma.GenericError:       "#d22323",
-		chroma.GenericEmph:        "italic",
-		chroma.GenericStrong:      "bold",
-		chroma.GenericPrompt:      "#aaaaaa",
-		chroma.GenericOutput:      "#cccccc",
-		chroma.GenericTraceback:   "#d22323",
-		chroma.GenericUnderline:   "underline",
-		chroma.Error:              "#a61717 bg: ", // "bg:#e3d2d2 #a61717",
-	}))
-
-	/*
-		s := styles.Get("hermit")
-		for _, t := range s.Types() {
-			e := s.Get(t)
-			fmt.Println(t, e)
-		}
-	*/
+// OpenText creates a viewer for a file of the given size, which is indexed
+// in the background. The file is closed with c, if not nil, when the viewer
+// is closed.
+func OpenText(r io.ReaderAt, size int64, c io.Closer, fpath string) Text {
+	return newText(newLineIndex(r, size, true), c, fpath)
+}
+
+func newText(idx *lineIndex, c io.Closer, fpath string) Text {
+	v := Text{
+		idx:    idx,
+		closer: c,
+		cache:  make(map[int64]string),
+	}
+	if l := matchLexer(fpath, idx); l != nil {
+		v.lexer = chroma.Coalesce(l)
+	}
+	return v
 }
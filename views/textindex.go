@@ -0,0 +1,229 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	linesPerMark = 64        // Lines between the offsets kept by the index
+	chunkSize    = 256 << 10 // Bytes read at a time while indexing
+	tailSize     = 64 << 10  // Bytes read from the end of the file for the tail
+	maxLine      = 16 << 10  // Longest line read for display
+	maxBlocks    = 256       // Blocks of line offsets kept in the cache
+)
+
+// lineIndex finds where the lines of a file start. To keep memory small, only
+// the offset of every linesPerMark'th line is kept; the offsets of the lines
+// in between are found again when needed. Until indexing is done, the lines
+// at the end of the file are found separately so they can be shown early.
+type lineIndex struct {
+	r      io.ReaderAt
+	size   int64
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	marks  []int64         // Offset of every linesPerMark'th line
+	lines  int             // Number of line starts found
+	done   bool            // Whether the whole file has been indexed
+	err    error           // Error that stopped indexing
+	tail   []int64         // Starts of the last lines, used until done
+	blocks map[int][]int64 // Cached line starts by block
+}
+
+// newLineIndex creates an index for the data in r. Indexing is done in the
+// background if asked, until it is stopped.
+func newLineIndex(r io.ReaderAt, size int64, background bool) *lineIndex {
+	idx := &lineIndex{
+		r:      r,
+		size:   size,
+		blocks: make(map[int][]int64),
+	}
+	if !background {
+		idx.index(context.Background())
+		return idx
+	}
+	idx.findTail()
+	ctx, cancel := context.WithCancel(context.Background())
+	idx.cancel = cancel
+	go idx.index(ctx)
+	return idx
+}
+
+// index scans the data for line starts.
+func (idx *lineIndex) index(ctx context.Context) {
+	var (
+		buf   = make([]byte, chunkSize)
+		off   int64
+		lines = 1 // Line 0 starts at offset 0
+		marks = []int64{0}
+	)
+	for off < idx.size {
+		if ctx.Err() != nil {
+			return
+		}
+		n, err := idx.r.ReadAt(buf[:min(int64(len(buf)), idx.size-off)], off)
+		b := buf[:n]
+		for i := 0; ; {
+			j := bytes.IndexByte(b[i:], '\n')
+			if j < 0 {
+				break
+			}
+			i += j + 1
+			if start := off + int64(i); start < idx.size {
+				if lines%linesPerMark == 0 {
+					marks = append(marks, start)
+				}
+				lines++
+			}
+		}
+		off += int64(n)
+
+		idx.mu.Lock()
+		idx.marks = append(idx.marks, marks...)
+		idx.lines = lines
+		if err != nil && err != io.EOF {
+			idx.err = err
+		}
+		idx.mu.Unlock()
+		marks = marks[:0]
+
+		if err != nil {
+			break
+		}
+	}
+	idx.mu.Lock()
+	if idx.lines == 0 {
+		// Empty data still has one empty line
+		idx.marks = append(idx.marks, 0)
+		idx.lines = 1
+	}
+	idx.done = true
+	idx.tail = nil
+	idx.mu.Unlock()
+}
+
+// findTail finds the starts of the lines at the end of the data.
+func (idx *lineIndex) findTail() {
+	start := max(idx.size-tailSize, 0)
+	buf := make([]byte, idx.size-start)
+	n, err := idx.r.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return
+	}
+	buf = buf[:n]
+	var tail []int64
+	if start == 0 {
+		tail = append(tail, 0)
+	}
+	for i, c := range buf {
+		if c == '\n' && start+int64(i)+1 < idx.size {
+			tail = append(tail, start+int64(i)+1)
+		}
+	}
+	idx.tail = tail
+}
+
+// stop stops indexing.
+func (idx *lineIndex) stop() {
+	if idx.cancel != nil {
+		idx.cancel()
+	}
+}
+
+// status returns the number of lines that have been indexed, the number of
+// lines found at the end of the data, whether indexing is done, and any error
+// that stopped it. While indexing, the last line found is not yet complete.
+func (idx *lineIndex) status() (lines, tail int, done bool, err error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.done {
+		return idx.lines, 0, true, idx.err
+	}
+	return max(idx.lines-1, 0), len(idx.tail), false, idx.err
+}
+
+// bounds returns where line i starts and ends, not counting the newline.
+// If tail is true, i counts lines in the tail instead.
+func (idx *lineIndex) bounds(i int, tail bool) (int64, int64, bool) {
+	if tail {
+		idx.mu.Lock()
+		defer idx.mu.Unlock()
+		if i < 0 || i >= len(idx.tail) {
+			return 0, 0, false
+		}
+		end := idx.size
+		if i+1 < len(idx.tail) {
+			end = idx.tail[i+1] - 1
+		}
+		return idx.tail[i], end, true
+	}
+
+	starts := idx.block(i / linesPerMark)
+	k := i % linesPerMark
+	if k >= len(starts) {
+		return 0, 0, false
+	}
+	end := idx.size
+	if k+1 < len(starts) {
+		end = starts[k+1] - 1
+	}
+	return starts[k], end, true
+}
+
+// block returns the starts of the lines in block b, followed by the start of
+// the first line of the next block, if there is one.
+func (idx *lineIndex) block(b int) []int64 {
+	idx.mu.Lock()
+	if starts, ok := idx.blocks[b]; ok {
+		idx.mu.Unlock()
+		return starts
+	}
+	if b < 0 || b >= len(idx.marks) {
+		idx.mu.Unlock()
+		return nil
+	}
+	off := idx.marks[b]
+	idx.mu.Unlock()
+
+	starts := []int64{off}
+	buf := make([]byte, 32<<10)
+	for len(starts) <= linesPerMark && off < idx.size {
+		n, err := idx.r.ReadAt(buf[:min(int64(len(buf)), idx.size-off)], off)
+		for i, c := range buf[:n] {
+			if c == '\n' && off+int64(i)+1 < idx.size {
+				starts = append(starts, off+int64(i)+1)
+				if len(starts) > linesPerMark {
+					break
+				}
+			}
+		}
+		off += int64(n)
+		if err != nil {
+			break
+		}
+	}
+
+	idx.mu.Lock()
+	if len(idx.blocks) >= maxBlocks {
+		clear(idx.blocks)
+	}
+	idx.blocks[b] = starts
+	idx.mu.Unlock()
+	return starts
+}
+
+// read returns the text between start and end, up to maxLine bytes,
+// without a final newline.
+func (idx *lineIndex) read(start, end int64) string {
+	end = min(end, start+maxLine)
+	if end <= start {
+		return ""
+	}
+	buf := make([]byte, end-start)
+	n, _ := idx.r.ReadAt(buf, start)
+	return strings.TrimSuffix(string(buf[:n]), "\n")
+}
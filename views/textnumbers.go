@@ -0,0 +1,62 @@
+package views
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// gutter is the style of the line numbers, which is not changed by the
+// style of the line at the cursor.
+var gutter = lipgloss.NewStyle().Faint(true)
+
+// LineNumbers reports whether line numbers are shown.
+func (v Text) LineNumbers() bool {
+	return v.numbers
+}
+
+// SetLineNumbers shows or hides the line numbers.
+func (v *Text) SetLineNumbers(on bool) {
+	v.numbers = on
+}
+
+// gutterWidth returns the width of the line numbers and the space after
+// them, which grows with the number of lines, or 0 if they are not shown.
+func (v Text) gutterWidth(width int) int {
+	if !v.numbers {
+		return 0
+	}
+	w := len(strconv.Itoa(v.lines())) + 1
+	if w >= width {
+		return 0
+	}
+	return w
+}
+
+// textWidth returns the width left for text in a view of the given width.
+func (v Text) textWidth(width int) int {
+	return width - v.gutterWidth(width)
+}
+
+// renderGutter renders the line number shown at position i. Continued
+// lines and lines whose number is not known yet get an empty gutter.
+func (v Text) renderGutter(i, width int) string {
+	w := v.gutterWidth(width)
+	if w == 0 {
+		return ""
+	}
+	head, _, done, _ := v.idx.status()
+	if !done && i >= head {
+		return strings.Repeat(" ", w)
+	}
+	if v.wrap != nil {
+		line, part := v.wrapped(i, width)
+		if part > 0 {
+			return strings.Repeat(" ", w)
+		}
+		i = line
+	}
+	return gutter.Render(fmt.Sprintf("%*d ", w-1, i+1))
+}
@@ -0,0 +1,225 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// wrapTable counts the visual lines of the text when wrapped to a width.
+// The lines are measured in the background, a block at a time; until that
+// is done, lines past those measured count as one visual line each. To keep
+// memory small, only the count before every linesPerMark'th line is kept;
+// the heights of the lines in between are measured again when needed.
+type wrapTable struct {
+	mu     sync.Mutex
+	state  wrapState
+	cancel context.CancelFunc // Stops measuring
+	blocks map[int][]int      // Cached heights of the lines by block
+}
+
+// wrapState is what is known of the visual lines at one time.
+type wrapState struct {
+	width    int   // Width the lines are wrapped to
+	marks    []int // Visual lines before every linesPerMark'th line measured
+	measured int   // Lines measured, which are the first ones
+	total    int   // Visual lines of the lines measured
+	done     bool  // Whether every line has been measured
+}
+
+// Wrapped reports whether long lines are wrapped.
+func (v Text) Wrapped() bool {
+	return v.wrap != nil
+}
+
+// Wrapping reports whether long lines are still being measured to wrap
+// them.
+func (v Text) Wrapping() bool {
+	if v.wrap == nil {
+		return false
+	}
+	v.wrap.mu.Lock()
+	defer v.wrap.mu.Unlock()
+	return !v.wrap.state.done
+}
+
+// SetWrap turns wrapping of long lines on or off. Wrapping needs the whole
+// file to be indexed.
+func (v *Text) SetWrap(on bool) error {
+	if !on {
+		v.wrap.stop()
+		v.wrap = nil
+		return nil
+	}
+	if _, _, done, _ := v.idx.status(); !done {
+		return errors.New("lines can be wrapped once indexing is done")
+	}
+	v.wrap = &wrapTable{}
+	v.pan = 0
+	return nil
+}
+
+// stop stops measuring lines.
+func (t *wrapTable) stop() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// Pan scrolls the text sideways by delta columns when lines are not wrapped.
+func (v *Text) Pan(delta int) {
+	if v.wrap == nil {
+		v.pan = min(max(v.pan+delta, 0), maxLine)
+	}
+}
+
+// LineOf returns the line shown at position pos.
+func (v Text) LineOf(pos, width int) int {
+	if v.wrap == nil || width <= 0 {
+		return pos
+	}
+	line, _ := v.wrapped(pos, width)
+	return line
+}
+
+// PosOf returns the first position that shows the line.
+func (v Text) PosOf(line, width int) int {
+	if v.wrap == nil || width <= 0 {
+		return line
+	}
+	t := v.table(width)
+	if line >= t.measured {
+		if t.done {
+			return max(t.total-1, 0)
+		}
+		return t.total + line - t.measured
+	}
+	b := line / linesPerMark
+	pos := t.marks[b]
+	for _, h := range v.heights(t, b)[:line%linesPerMark] {
+		pos += h
+	}
+	return pos
+}
+
+// wrapped returns the line shown at position pos and which of its visual
+// lines it is.
+func (v Text) wrapped(pos, width int) (line, part int) {
+	t := v.table(width)
+	if !t.done && pos >= t.total {
+		return t.measured + pos - t.total, 0
+	}
+	b := sort.Search(len(t.marks), func(b int) bool { return t.marks[b] > pos }) - 1
+	if b < 0 {
+		return 0, 0
+	}
+	rest := pos - t.marks[b]
+	line = b * linesPerMark
+	for _, h := range v.heights(t, b) {
+		if rest < h {
+			return line, rest
+		}
+		rest -= h
+		line++
+	}
+	return line - 1, 0
+}
+
+// cut returns the columns of a rendered line that are shown at position i.
+func (v Text) cut(s string, i, width int) string {
+	tw := v.textWidth(width)
+	if v.wrap == nil {
+		return ansi.Cut(s, v.pan, v.pan+tw)
+	}
+	_, part := v.wrapped(i, width)
+	return ansi.Cut(s, part*tw, (part+1)*tw)
+}
+
+// table returns what is known of the visual lines for a view of the given
+// width, starting to measure them again if the width left for the text has
+// changed.
+func (v Text) table(width int) wrapState {
+	width = max(v.textWidth(width), 1)
+	t := v.wrap
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state.width != width {
+		if t.cancel != nil {
+			t.cancel()
+		}
+		var ctx context.Context
+		ctx, t.cancel = context.WithCancel(context.Background())
+		t.state = wrapState{width: width}
+		t.blocks = make(map[int][]int)
+		go v.measure(ctx, t, width)
+	}
+	return t.state
+}
+
+// measure counts the visual lines of the text, a block at a time, until it
+// is done or stopped.
+func (v Text) measure(ctx context.Context, t *wrapTable, width int) {
+	lines := v.lines()
+	total := 0
+	for b := 0; b*linesPerMark < lines; b++ {
+		mark := total
+		end := min((b+1)*linesPerMark, lines)
+		for i := b * linesPerMark; i < end; i++ {
+			total += v.height(i, width)
+		}
+		t.mu.Lock()
+		if ctx.Err() != nil {
+			t.mu.Unlock()
+			return
+		}
+		t.state.marks = append(t.state.marks, mark)
+		t.state.measured = end
+		t.state.total = total
+		t.mu.Unlock()
+	}
+	t.mu.Lock()
+	if ctx.Err() == nil {
+		t.state.done = true
+	}
+	t.mu.Unlock()
+}
+
+// heights returns the heights of the lines in block b.
+func (v Text) heights(s wrapState, b int) []int {
+	t := v.wrap
+	t.mu.Lock()
+	hs, ok := t.blocks[b]
+	ok = ok && t.state.width == s.width
+	t.mu.Unlock()
+	if ok {
+		return hs
+	}
+	hs = nil
+	lines := v.lines()
+	for i := b * linesPerMark; i < min((b+1)*linesPerMark, lines); i++ {
+		hs = append(hs, v.height(i, s.width))
+	}
+	t.mu.Lock()
+	if t.state.width == s.width {
+		if len(t.blocks) >= maxBlocks {
+			clear(t.blocks)
+		}
+		t.blocks[b] = hs
+	}
+	t.mu.Unlock()
+	return hs
+}
+
+// height returns the number of visual lines of line i.
+func (v Text) height(i, width int) int {
+	line, _ := v.Line(i)
+	return max((ansi.StringWidth(line)+width-1)/width, 1)
+}